@@ -0,0 +1,254 @@
+package diff
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/GraveSIN/http-probe/internal/dnsprobe"
+	"github.com/GraveSIN/http-probe/internal/probe"
+)
+
+// fields left out of the comparison, as they change on every scan or repeat another field
+var ignoredFields = map[string]bool{
+	"time_taken":  true,
+	"status_code": true,
+}
+
+// normalizeHTTPResult returns a copy of the result with the nested fields that change on every
+// scan cleared (the expiry of cookies set relative to the request and the QUIC handshake times),
+// and the technologies and Alt-Svc alternatives sorted, as their order means nothing
+func normalizeHTTPResult(result probe.ProbeResult) probe.ProbeResult {
+	result.Cookies = slices.Clone(result.Cookies)
+	for i := range result.Cookies {
+		result.Cookies[i].Expires = nil
+	}
+	result.HTTP3 = slices.Clone(result.HTTP3)
+	for i := range result.HTTP3 {
+		result.HTTP3[i].HandshakeTime = 0
+	}
+	result.Technologies = sorted(result.Technologies)
+	result.AltSvc = slices.SortedFunc(slices.Values(result.AltSvc), func(a, b probe.AltService) int {
+		return cmp.Or(
+			cmp.Compare(a.Protocol, b.Protocol),
+			cmp.Compare(a.Host, b.Host),
+			cmp.Compare(a.Port, b.Port),
+			cmp.Compare(a.MaxAge, b.MaxAge),
+		)
+	})
+	return result
+}
+
+// normalizeDNSResult returns a copy of the result with its records sorted, as resolvers rotate them
+func normalizeDNSResult(result dnsprobe.DNSProbeResult) dnsprobe.DNSProbeResult {
+	result.TXTRecords = sorted(result.TXTRecords)
+	result.NSRecords = sorted(result.NSRecords)
+	result.ARecords = sorted(result.ARecords)
+	result.AAAARecords = sorted(result.AAAARecords)
+	result.MXRecords = sorted(result.MXRecords)
+	return result
+}
+
+// sorted returns a sorted copy of values
+func sorted(values []string) []string {
+	return slices.Sorted(slices.Values(values))
+}
+
+// Kinds of assets
+const (
+	KindHTTP = "http"
+	KindDNS  = "dns"
+)
+
+// Asset is a result of a scan, keyed by URL for HTTP results and by domain for DNS results
+type Asset struct {
+	Key  string `json:"key"`
+	Kind string `json:"kind"`
+	// Result is the *probe.ProbeResult or *dnsprobe.DNSProbeResult the asset was loaded into
+	Result any `json:"result"`
+	fields map[string]json.RawMessage
+}
+
+// Scan holds the assets of a JSON lines output file, in file order
+type Scan struct {
+	Assets []*Asset
+}
+
+// Load reads the output of a scan written with --json, with HTTP and DNS results told apart by their url and domain fields
+func Load(filename string) (*Scan, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading scan %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	scan := &Scan{}
+	scanner := bufio.NewScanner(file)
+	// results with TLS scans, cookies or findings make long lines
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		asset, err := parseAsset(line)
+		if err != nil {
+			return nil, fmt.Errorf("[!] invalid result on line %d of %s: %w", lineNumber, filename, err)
+		}
+		scan.Assets = append(scan.Assets, asset)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error scanning %s: %w", filename, err)
+	}
+	return scan, nil
+}
+
+func parseAsset(line []byte) (*Asset, error) {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(line, &keys); err != nil {
+		return nil, err
+	}
+
+	asset := &Asset{}
+	// compared is what the fields are taken from, the loaded result without its volatile fields
+	var compared any
+	switch {
+	case keys["url"] != nil:
+		var result probe.ProbeResult
+		if err := json.Unmarshal(line, &result); err != nil {
+			return nil, err
+		}
+		// --all-ips writes one result per address for the same URL
+		asset.Key = result.URL
+		if result.DialAddress != "" {
+			asset.Key += " @ " + result.DialAddress
+		}
+		asset.Kind, asset.Result = KindHTTP, &result
+		compared = normalizeHTTPResult(result)
+	case keys["domain"] != nil:
+		var result dnsprobe.DNSProbeResult
+		if err := json.Unmarshal(line, &result); err != nil {
+			return nil, err
+		}
+		asset.Key, asset.Kind, asset.Result = result.Domain, KindDNS, &result
+		compared = normalizeDNSResult(result)
+	default:
+		return nil, fmt.Errorf("neither an HTTP nor a DNS result")
+	}
+
+	// compare the fields of the loaded result rather than of the line, so both sides are normalized alike
+	normalized, err := json.Marshal(compared)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(normalized, &fields); err != nil {
+		return nil, err
+	}
+	for field := range ignoredFields {
+		delete(fields, field)
+	}
+	asset.fields = make(map[string]json.RawMessage)
+	flatten("", fields, asset.fields)
+	return asset, nil
+}
+
+// flatten adds the fields of nested objects under dotted names (security_headers.grade), so changes
+// are reported for the nested field that changed. Arrays are compared as a whole.
+func flatten(prefix string, fields, flat map[string]json.RawMessage) {
+	for name, value := range fields {
+		var nested map[string]json.RawMessage
+		if bytes.HasPrefix(value, []byte("{")) && json.Unmarshal(value, &nested) == nil {
+			flatten(prefix+name+".", nested, flat)
+			continue
+		}
+		flat[prefix+name] = value
+	}
+}
+
+// FieldChange is a field whose value differs between two scans, null when absent from one of them
+type FieldChange struct {
+	Field string          `json:"field"`
+	Old   json.RawMessage `json:"old"`
+	New   json.RawMessage `json:"new"`
+}
+
+// Change is an asset found in both scans with different fields
+type Change struct {
+	Key    string        `json:"key"`
+	Kind   string        `json:"kind"`
+	Fields []FieldChange `json:"fields"`
+}
+
+// Report lists what changed between two scans
+type Report struct {
+	New     []*Asset `json:"new"`
+	Removed []*Asset `json:"removed"`
+	Changed []Change `json:"changed"`
+}
+
+// Compare matches the assets of two scans by kind and key and reports the new, removed and changed ones
+func Compare(old, new *Scan) *Report {
+	report := &Report{New: []*Asset{}, Removed: []*Asset{}, Changed: []Change{}}
+	oldAssets := index(old)
+	newAssets := index(new)
+
+	for _, asset := range new.Assets {
+		previous, ok := oldAssets[asset.Kind+"|"+asset.Key]
+		if !ok {
+			report.New = append(report.New, asset)
+			continue
+		}
+		if fields := compareFields(previous.fields, asset.fields); len(fields) > 0 {
+			report.Changed = append(report.Changed, Change{Key: asset.Key, Kind: asset.Kind, Fields: fields})
+		}
+	}
+	for _, asset := range old.Assets {
+		if _, ok := newAssets[asset.Kind+"|"+asset.Key]; !ok {
+			report.Removed = append(report.Removed, asset)
+		}
+	}
+	return report
+}
+
+// index maps the assets of a scan by kind and key, keeping the last result of a repeated key
+func index(scan *Scan) map[string]*Asset {
+	assets := make(map[string]*Asset, len(scan.Assets))
+	for _, asset := range scan.Assets {
+		assets[asset.Kind+"|"+asset.Key] = asset
+	}
+	return assets
+}
+
+func compareFields(old, new map[string]json.RawMessage) []FieldChange {
+	names := make(map[string]bool)
+	for name := range old {
+		names[name] = true
+	}
+	for name := range new {
+		names[name] = true
+	}
+
+	var changes []FieldChange
+	for _, name := range slices.Sorted(maps.Keys(names)) {
+		oldValue, newValue := cmpValue(old[name]), cmpValue(new[name])
+		if !bytes.Equal(oldValue, newValue) {
+			changes = append(changes, FieldChange{Field: name, Old: oldValue, New: newValue})
+		}
+	}
+	return changes
+}
+
+// cmpValue returns null for absent fields
+func cmpValue(value json.RawMessage) json.RawMessage {
+	if value == nil {
+		return json.RawMessage("null")
+	}
+	return value
+}
@@ -0,0 +1,159 @@
+package diff
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/GraveSIN/http-probe/internal/dnsprobe"
+	"github.com/GraveSIN/http-probe/internal/probe"
+)
+
+func writeScan(t *testing.T, name, content string) *Scan {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	scan, err := Load(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return scan
+}
+
+func TestCompare(t *testing.T) {
+	old := writeScan(t, "old.jsonl", `{"url":"https://a.example.com","status_code":200,"status_line":"200 OK","title":"A","time_taken":100,"security_headers":{"grade":"B","score":80}}
+{"url":"https://b.example.com","status_code":200,"status_line":"200 OK","title":"B","time_taken":100}
+{"domain":"example.com","a_records":["192.0.2.1"]}
+`)
+	new := writeScan(t, "new.jsonl", `{"url":"https://a.example.com","status_code":302,"status_line":"302 Found","title":"A","time_taken":250,"security_headers":{"grade":"B","score":85}}
+
+{"url":"https://c.example.com","status_code":200,"status_line":"200 OK","title":"C","time_taken":100}
+{"domain":"example.com","a_records":["192.0.2.1"],"aaaa_records":["2001:db8::1"]}
+`)
+
+	report := Compare(old, new)
+
+	keys := func(assets []*Asset) (keys []string) {
+		for _, asset := range assets {
+			keys = append(keys, asset.Key)
+		}
+		return keys
+	}
+	if got := keys(report.New); !slices.Equal(got, []string{"https://c.example.com"}) {
+		t.Errorf("expected c to be new, got %v", got)
+	}
+	if got := keys(report.Removed); !slices.Equal(got, []string{"https://b.example.com"}) {
+		t.Errorf("expected b to be removed, got %v", got)
+	}
+
+	expected := map[string][]string{
+		"https://a.example.com": {"security_headers.score", "status_line"},
+		"example.com":           {"aaaa_records"},
+	}
+	if len(report.Changed) != len(expected) {
+		t.Fatalf("expected %d changed assets, got %+v", len(expected), report.Changed)
+	}
+	for _, change := range report.Changed {
+		var fields []string
+		for _, field := range change.Fields {
+			fields = append(fields, field.Field)
+		}
+		if !slices.Equal(fields, expected[change.Key]) {
+			t.Errorf("%s: expected changed fields %v, got %v", change.Key, expected[change.Key], fields)
+		}
+	}
+
+	var markdown strings.Builder
+	if err := report.Write(&markdown, FormatMarkdown); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"1 new, 1 removed, 2 changed", "| status_line | 200 OK | 302 Found |", "| aaaa_records | (none) | [\"2001:db8::1\"] |"} {
+		if !strings.Contains(markdown.String(), line) {
+			t.Errorf("expected %q in:\n%s", line, markdown.String())
+		}
+	}
+}
+
+func TestCompareIgnoresVolatileFields(t *testing.T) {
+	old := writeScan(t, "old.jsonl", `{"url":"https://a.example.com","status_code":200,"status_line":"200 OK","time_taken":100,"cookies":[{"name":"session","secure":true,"http_only":true,"expires":"2026-10-18T10:00:00Z","max_age":3600,"size":20}],"http3":[{"endpoint":"a.example.com:443","success":true,"handshake_time":1200000,"alpn":"h3"}]}
+`)
+	new := writeScan(t, "new.jsonl", `{"url":"https://a.example.com","status_code":200,"status_line":"200 OK","time_taken":100,"cookies":[{"name":"session","secure":true,"http_only":true,"expires":"2026-10-19T10:00:00Z","max_age":3600,"size":20}],"http3":[{"endpoint":"a.example.com:443","success":true,"handshake_time":3400000,"alpn":"h3"}]}
+`)
+
+	if report := Compare(old, new); len(report.Changed) != 0 {
+		t.Errorf("expected no change, got %+v", report.Changed)
+	}
+
+	// the results written in the report keep the volatile fields
+	result := new.Assets[0].Result.(*probe.ProbeResult)
+	if result.Cookies[0].Expires == nil || result.HTTP3[0].HandshakeTime == 0 {
+		t.Errorf("expected the loaded result to keep its cookie expiry and handshake time, got %+v", result)
+	}
+
+	// other changes to the same nested values are still reported
+	changed := writeScan(t, "changed.jsonl", `{"url":"https://a.example.com","status_code":200,"status_line":"200 OK","time_taken":100,"cookies":[{"name":"session","secure":false,"http_only":true,"expires":"2026-10-19T10:00:00Z","max_age":3600,"size":20}],"http3":[{"endpoint":"a.example.com:443","success":true,"handshake_time":3400000,"alpn":"h3"}]}
+`)
+	report := Compare(old, changed)
+	if len(report.Changed) != 1 || len(report.Changed[0].Fields) != 1 || report.Changed[0].Fields[0].Field != "cookies" {
+		t.Errorf("expected cookies to change, got %+v", report.Changed)
+	}
+}
+
+func TestCompareIgnoresOrder(t *testing.T) {
+	old := writeScan(t, "old.jsonl", `{"domain":"a.example.com","a_records":["192.0.2.1","192.0.2.2"],"ns_records":["ns1.example.com.","ns2.example.com."]}
+{"url":"https://a.example.com","status_code":200,"status_line":"200 OK","time_taken":100,"technologies":["PHP","Java"],"alt_svc":[{"protocol":"h3","port":"443"},{"protocol":"h3-29","port":"443"}]}
+`)
+	new := writeScan(t, "new.jsonl", `{"domain":"a.example.com","a_records":["192.0.2.2","192.0.2.1"],"ns_records":["ns2.example.com.","ns1.example.com."]}
+{"url":"https://a.example.com","status_code":200,"status_line":"200 OK","time_taken":100,"technologies":["Java","PHP"],"alt_svc":[{"protocol":"h3-29","port":"443"},{"protocol":"h3","port":"443"}]}
+`)
+
+	if report := Compare(old, new); len(report.Changed) != 0 {
+		t.Errorf("expected reordered records to be no change, got %+v", report.Changed)
+	}
+
+	// the report keeps the records in the order they were written
+	result := new.Assets[0].Result.(*dnsprobe.DNSProbeResult)
+	if !slices.Equal(result.ARecords, []string{"192.0.2.2", "192.0.2.1"}) {
+		t.Errorf("expected the loaded result to keep its order, got %v", result.ARecords)
+	}
+
+	changed := writeScan(t, "changed.jsonl", `{"domain":"a.example.com","a_records":["192.0.2.3","192.0.2.1"],"ns_records":["ns2.example.com.","ns1.example.com."]}
+`)
+	report := Compare(old, changed)
+	if len(report.Changed) != 1 || len(report.Changed[0].Fields) != 1 || report.Changed[0].Fields[0].Field != "a_records" {
+		t.Errorf("expected a_records to change, got %+v", report.Changed)
+	}
+}
+
+func TestCompareFailedTarget(t *testing.T) {
+	old := writeScan(t, "old.jsonl", `{"url":"https://a.example.com","status_code":200,"status_line":"200 OK","title":"A","time_taken":100}
+`)
+	new := writeScan(t, "new.jsonl", `{"url":"https://a.example.com","status_code":0,"status_line":"","content_length":0,"time_taken":0,"error":"dial tcp 192.0.2.1:443: connect: connection refused"}
+`)
+
+	report := Compare(old, new)
+	if len(report.Removed) != 0 || len(report.Changed) != 1 {
+		t.Fatalf("expected a host that stopped answering to be changed, got %+v", report)
+	}
+	var fields []string
+	for _, field := range report.Changed[0].Fields {
+		fields = append(fields, field.Field)
+	}
+	if !slices.Equal(fields, []string{"error", "status_line", "title"}) {
+		t.Errorf("unexpected changed fields %v", fields)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "scan.jsonl")
+	if err := os.WriteFile(filename, []byte(`{"url":"https://a.example.com"}`+"\n"+`{"status":1}`+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(filename); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected an error on line 2, got %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/GraveSIN/http-probe/internal/dnsprobe"
+	"github.com/GraveSIN/http-probe/internal/probe"
+	"github.com/fatih/color"
+)
+
+// Output formats of a report
+const (
+	FormatHuman    = "human"
+	FormatJSON     = "json"
+	FormatMarkdown = "markdown"
+)
+
+// Write renders the report in one of the output formats
+func (r *Report) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatHuman:
+		return r.writeHuman(w)
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r)
+	case FormatMarkdown:
+		return r.writeMarkdown(w)
+	}
+	return fmt.Errorf("[!] invalid format %q, expected human, json or markdown", format)
+}
+
+func (r *Report) writeHuman(w io.Writer) error {
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	var output strings.Builder
+	for _, asset := range r.New {
+		fmt.Fprintf(&output, "%s %s %s\n", green("[+]"), asset.Key, summary(asset))
+	}
+	for _, asset := range r.Removed {
+		fmt.Fprintf(&output, "%s %s %s\n", red("[-]"), asset.Key, summary(asset))
+	}
+	for _, change := range r.Changed {
+		fmt.Fprintf(&output, "%s %s\n", yellow("[~]"), change.Key)
+		for _, field := range change.Fields {
+			fmt.Fprintf(&output, "|   %s: %s -> %s\n", field.Field, displayValue(field.Old), displayValue(field.New))
+		}
+	}
+	fmt.Fprintf(&output, "%d new, %d removed, %d changed\n", len(r.New), len(r.Removed), len(r.Changed))
+
+	_, err := io.WriteString(w, output.String())
+	return err
+}
+
+func (r *Report) writeMarkdown(w io.Writer) error {
+	var output strings.Builder
+	fmt.Fprintf(&output, "# Scan diff\n\n%d new, %d removed, %d changed\n", len(r.New), len(r.Removed), len(r.Changed))
+
+	writeAssets := func(title string, assets []*Asset) {
+		if len(assets) == 0 {
+			return
+		}
+		fmt.Fprintf(&output, "\n## %s (%d)\n\n", title, len(assets))
+		for _, asset := range assets {
+			fmt.Fprintf(&output, "- `%s` %s\n", asset.Key, markdownEscape(summary(asset)))
+		}
+	}
+	writeAssets("New", r.New)
+	writeAssets("Removed", r.Removed)
+
+	if len(r.Changed) > 0 {
+		fmt.Fprintf(&output, "\n## Changed (%d)\n", len(r.Changed))
+		for _, change := range r.Changed {
+			fmt.Fprintf(&output, "\n### `%s`\n\n| Field | Old | New |\n| --- | --- | --- |\n", change.Key)
+			for _, field := range change.Fields {
+				fmt.Fprintf(&output, "| %s | %s | %s |\n", field.Field,
+					markdownEscape(displayValue(field.Old)), markdownEscape(displayValue(field.New)))
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, output.String())
+	return err
+}
+
+// summary describes an asset in a few words: status and title for HTTP, addresses for DNS
+func summary(asset *Asset) string {
+	switch result := asset.Result.(type) {
+	case *probe.ProbeResult:
+		if result.Skipped != "" {
+			return "[skipped: " + result.Skipped + "]"
+		}
+		parts := []string{"[" + result.StatusLine + "]"}
+		if title := strings.TrimSpace(result.Title); title != "" {
+			parts = append(parts, "["+title+"]")
+		}
+		return strings.Join(parts, " ")
+	case *dnsprobe.DNSProbeResult:
+		return "[" + strings.Join(slices.Concat(result.ARecords, result.AAAARecords), ", ") + "]"
+	}
+	return ""
+}
+
+// displayValue shows strings without quotes, absent fields as (none) and other values as JSON
+func displayValue(value json.RawMessage) string {
+	if string(value) == "null" {
+		return "(none)"
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		return s
+	}
+	return string(value)
+}
+
+func markdownEscape(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
@@ -2,12 +2,14 @@ package dnsprobe
 
 import (
 	"bufio"
+	"cmp"
 	"context"
 	"fmt"
 	"os"
 	"sync"
 	"time"
 
+	"github.com/GraveSIN/http-probe/internal/source"
 	"github.com/GraveSIN/http-probe/internal/utils"
 	"github.com/spf13/cobra"
 	"net"
@@ -18,15 +20,23 @@ type DNSProbeConfig struct {
 	Threads    int
 	OutputFile string
 	Timeout    int
+	JSONOutput bool
+	// IPNetwork restricts address lookups to "ip4" (A) or "ip6" (AAAA), "" looks up both
+	IPNetwork string
+	DualStack bool
+	// Sources are the local addresses to send queries from, nil to let the system choose
+	Sources *source.Pool
 }
 
 type DNSProbeResult struct {
-	Domain      string
-	TXTRecords  []string
-	NSRecords   []string
-	ARecords    []string
-	AAAARecords []string
-	MXRecords   []string
+	Domain      string   `json:"domain"`
+	TXTRecords  []string `json:"txt_records,omitempty"`
+	NSRecords   []string `json:"ns_records,omitempty"`
+	ARecords    []string `json:"a_records,omitempty"`
+	AAAARecords []string `json:"aaaa_records,omitempty"`
+	MXRecords   []string `json:"mx_records,omitempty"`
+	// DualStack is set in --dual-stack mode, true when the domain has both A and AAAA records
+	DualStack *bool `json:"dual_stack,omitempty"`
 }
 
 type DNSProber struct {
@@ -43,6 +53,22 @@ func ParseDNSProbeConfig(cmd *cobra.Command) (*DNSProbeConfig, error) {
 	threads, _ := cmd.Flags().GetInt("threads")
 	timeout, _ := cmd.Flags().GetInt("timeout")
 	outputFile, _ := cmd.Flags().GetString("output")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	ipv4, _ := cmd.Flags().GetBool("ipv4")
+	ipv6, _ := cmd.Flags().GetBool("ipv6")
+	dualStack, _ := cmd.Flags().GetBool("dual-stack")
+	sourceIPs, _ := cmd.Flags().GetStringSlice("source-ip")
+	iface, _ := cmd.Flags().GetString("interface")
+
+	ipNetwork, err := utils.IPNetwork(ipv4, ipv6, dualStack)
+	if err != nil {
+		return nil, err
+	}
+
+	sources, err := source.New(sourceIPs, iface)
+	if err != nil {
+		return nil, err
+	}
 
 	// Domains from file
 	if domainFile != "" {
@@ -76,17 +102,16 @@ func ParseDNSProbeConfig(cmd *cobra.Command) (*DNSProbeConfig, error) {
 		Threads:    threads,
 		OutputFile: outputFile,
 		Timeout: timeout,
+		JSONOutput: jsonOutput,
+		IPNetwork:  ipNetwork,
+		DualStack:  dualStack,
+		Sources:    sources,
 	}, nil
 }
 
 func NewDNSProber(config *DNSProbeConfig) *DNSProber {
 	resolver := &net.Resolver{
-		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
-			d := net.Dialer{
-				Timeout: time.Duration(config.Timeout) * time.Second,
-			}
-			return d.DialContext(ctx, "udp", "1.1.1.1:53")
-		},
+		Dial: config.Sources.ResolverDial("1.1.1.1:53", time.Duration(config.Timeout)*time.Second),
 	}
 
 	return &DNSProber{
@@ -122,7 +147,7 @@ func (p *DNSProber) worker() {
 	defer p.waitGroup.Done()
 
 	for domain := range p.workPool {
-		result := p.dnsProbeDomain(domain)
+		result := p.ProbeDomain(domain)
 		p.results <- result
 	}
 }
@@ -132,7 +157,8 @@ func (p *DNSProber) waitAndClose() {
 	close(p.results)
 }
 
-func (p *DNSProber) dnsProbeDomain(domain string) DNSProbeResult {
+// ProbeDomain looks up the records of a single domain, for callers that probe domains one at a time instead of through Start
+func (p *DNSProber) ProbeDomain(domain string) DNSProbeResult {
 	if err := utils.ValidateDomain(domain); err != nil {
 		return DNSProbeResult{}
 	}
@@ -159,18 +185,22 @@ func (p *DNSProber) dnsProbeDomain(domain string) DNSProbeResult {
 
 	ipCtx, ipCancel := context.WithTimeout(context.Background(), time.Duration(p.config.Timeout)*time.Second)
 	defer ipCancel()
-	if aRecords, err := p.resolver.LookupIPAddr(ipCtx, domain); err == nil {
+	if aRecords, err := p.resolver.LookupIP(ipCtx, cmp.Or(p.config.IPNetwork, "ip"), domain); err == nil {
 		var ip4, ip6 []string
 		for _, ip := range aRecords {
-			if ip.IP.To4() != nil {
-				 ip4 = append(ip4, ip.IP.String())
+			if ip.To4() != nil {
+				ip4 = append(ip4, ip.String())
 			} else {
-				ip6 = append(ip6, ip.IP.String())
+				ip6 = append(ip6, ip.String())
 			}
 		}
 		result.ARecords = ip4
 		result.AAAARecords = ip6
 	}
+	if p.config.DualStack {
+		dualStack := len(result.ARecords) > 0 && len(result.AAAARecords) > 0
+		result.DualStack = &dualStack
+	}
 
 	mxCtx, mxCancel := context.WithTimeout(context.Background(), time.Duration(p.config.Timeout)*time.Second)
 	defer mxCancel()
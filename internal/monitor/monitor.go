@@ -0,0 +1,249 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GraveSIN/http-probe/internal/dnsprobe"
+	"github.com/GraveSIN/http-probe/internal/probe"
+)
+
+// Config holds the options of a monitor
+type Config struct {
+	URLs     []string
+	Interval time.Duration
+	Threads  int
+	// Timeout in seconds for each request or DNS record's resolution
+	Timeout int
+	// StateFile keeps the last observations across runs, none when empty
+	StateFile string
+	Sinks     []Sink
+}
+
+// Observation is the last-seen state of a URL
+type Observation struct {
+	Result probe.ProbeResult `json:"result"`
+	// DNS is nil for URLs whose host is an IP address
+	DNS *dnsprobe.DNSProbeResult `json:"dns,omitempty"`
+}
+
+// Event reports a field of a URL that changed between two observations
+type Event struct {
+	Time  time.Time `json:"time"`
+	URL   string    `json:"url"`
+	Field string    `json:"field"`
+	Old   string    `json:"old"`
+	New   string    `json:"new"`
+}
+
+// Monitor re-probes a list of URLs on an interval and emits an event for every change
+type Monitor struct {
+	config    *Config
+	prober    *probe.Prober
+	dnsProber *dnsprobe.DNSProber
+
+	mu           sync.Mutex
+	observations map[string]Observation
+}
+
+// New creates a monitor, loading the observations of the state file when it exists
+func New(config *Config) (*Monitor, error) {
+	m := &Monitor{
+		config: config,
+		prober: probe.NewProber(&probe.ProberConfig{
+			URLs:    &[]string{},
+			Threads: config.Threads,
+			Timeout: config.Timeout,
+			Method:  "GET",
+			Hashes:  true,
+		}),
+		dnsProber: dnsprobe.NewDNSProber(&dnsprobe.DNSProbeConfig{
+			Domains: &[]string{},
+			Threads: 1,
+			Timeout: config.Timeout,
+		}),
+		observations: make(map[string]Observation),
+	}
+
+	if config.StateFile != "" {
+		data, err := os.ReadFile(config.StateFile)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("error reading state file %s: %w", config.StateFile, err)
+		}
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &m.observations); err != nil {
+				return nil, fmt.Errorf("[!] invalid state file %s: %w", config.StateFile, err)
+			}
+		}
+	}
+	return m, nil
+}
+
+// Run probes every URL right away, then on every interval until ctx is done
+func (m *Monitor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.Cycle(ctx); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Cycle probes every URL once, sends the change events to the sinks and saves the state
+func (m *Monitor) Cycle(ctx context.Context) error {
+	urls := make(chan string)
+	var waitGroup sync.WaitGroup
+	for range max(m.config.Threads, 1) {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for url := range urls {
+				m.observe(url)
+			}
+		}()
+	}
+
+	// once ctx is done no URL is handed out, even to an idle worker; the rest wait for the next run
+send:
+	for _, url := range m.config.URLs {
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case urls <- url:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(urls)
+	waitGroup.Wait()
+
+	return m.saveState()
+}
+
+// observe probes url and compares the result with its last observation
+func (m *Monitor) observe(url string) {
+	var observation Observation
+	if results := m.prober.Probe(url); len(results) > 0 {
+		observation.Result = results[0]
+	}
+	if host := hostname(url); host != "" && net.ParseIP(host) == nil {
+		dns := m.dnsProber.ProbeDomain(host)
+		observation.DNS = &dns
+	}
+
+	m.mu.Lock()
+	previous, seen := m.observations[url]
+	m.observations[url] = observation
+	m.mu.Unlock()
+
+	// the first observation is the baseline
+	if !seen {
+		return
+	}
+	now := time.Now()
+	for _, event := range changes(previous, observation) {
+		event.Time = now
+		event.URL = url
+		m.emit(event)
+	}
+}
+
+func (m *Monitor) emit(event Event) {
+	for _, sink := range m.config.Sinks {
+		if err := sink.Send(event); err != nil {
+			fmt.Fprintf(os.Stderr, "[!] failed to send event for %s: %v\n", event.URL, err)
+		}
+	}
+}
+
+// changes lists the fields that differ between two observations of a URL
+func changes(old, new Observation) []Event {
+	fields := []struct {
+		name  string
+		value func(Observation) string
+	}{
+		{"status", func(o Observation) string { return o.Result.StatusLine }},
+		{"title", func(o Observation) string { return strings.TrimSpace(o.Result.Title) }},
+		{"content_hash", func(o Observation) string { return o.Result.ContentSHA256 }},
+		{"certificate", func(o Observation) string { return o.Result.CertificateSHA256 }},
+		{"server", func(o Observation) string { return o.Result.ServerHeader }},
+		{"dns_a", dnsRecords(func(r *dnsprobe.DNSProbeResult) []string { return r.ARecords })},
+		{"dns_aaaa", dnsRecords(func(r *dnsprobe.DNSProbeResult) []string { return r.AAAARecords })},
+		{"dns_ns", dnsRecords(func(r *dnsprobe.DNSProbeResult) []string { return r.NSRecords })},
+		{"dns_mx", dnsRecords(func(r *dnsprobe.DNSProbeResult) []string { return r.MXRecords })},
+		{"dns_txt", dnsRecords(func(r *dnsprobe.DNSProbeResult) []string { return r.TXTRecords })},
+	}
+
+	var events []Event
+	for _, field := range fields {
+		if oldValue, newValue := field.value(old), field.value(new); oldValue != newValue {
+			events = append(events, Event{Field: field.name, Old: oldValue, New: newValue})
+		}
+	}
+	return events
+}
+
+// dnsRecords returns a field value listing records in a stable order, as resolvers rotate them
+func dnsRecords(records func(*dnsprobe.DNSProbeResult) []string) func(Observation) string {
+	return func(o Observation) string {
+		if o.DNS == nil {
+			return ""
+		}
+		sorted := slices.Sorted(slices.Values(records(o.DNS)))
+		return strings.Join(sorted, ",")
+	}
+}
+
+// saveState writes the observations to the state file, through a temporary file so it's never left half written
+func (m *Monitor) saveState() error {
+	if m.config.StateFile == "" {
+		return nil
+	}
+
+	m.mu.Lock()
+	data, err := json.Marshal(m.observations)
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(m.config.StateFile), ".http-probe-state-*")
+	if err != nil {
+		return fmt.Errorf("error writing state file %s: %w", m.config.StateFile, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing state file %s: %w", m.config.StateFile, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing state file %s: %w", m.config.StateFile, err)
+	}
+	return os.Rename(tmp.Name(), m.config.StateFile)
+}
+
+func hostname(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return parsed.Hostname()
+}
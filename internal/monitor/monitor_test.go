@@ -0,0 +1,138 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/GraveSIN/http-probe/internal/dnsprobe"
+	"github.com/GraveSIN/http-probe/internal/probe"
+)
+
+type recordingSink struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (s *recordingSink) Send(event Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+func TestChanges(t *testing.T) {
+	old := Observation{
+		Result: probe.ProbeResult{StatusLine: "200 OK", Title: "Home", ServerHeader: "nginx", ContentSHA256: "a"},
+		DNS:    &dnsprobe.DNSProbeResult{ARecords: []string{"192.0.2.1", "192.0.2.2"}},
+	}
+
+	tests := []struct {
+		name     string
+		update   func(*Observation)
+		expected []string
+	}{
+		{"unchanged", func(*Observation) {}, nil},
+		{"rotated records", func(o *Observation) {
+			o.DNS = &dnsprobe.DNSProbeResult{ARecords: []string{"192.0.2.2", "192.0.2.1"}}
+		}, nil},
+		{"down", func(o *Observation) { o.Result = probe.ProbeResult{} }, []string{"status", "title", "content_hash", "server"}},
+		{"new address", func(o *Observation) {
+			o.DNS = &dnsprobe.DNSProbeResult{ARecords: []string{"192.0.2.3"}}
+		}, []string{"dns_a"}},
+		{"new certificate and title", func(o *Observation) {
+			o.Result.CertificateSHA256 = "b"
+			o.Result.Title = " Maintenance "
+		}, []string{"title", "certificate"}},
+	}
+
+	for _, test := range tests {
+		current := old
+		test.update(&current)
+		var fields []string
+		for _, event := range changes(old, current) {
+			fields = append(fields, event.Field)
+		}
+		if !slices.Equal(fields, test.expected) {
+			t.Errorf("%s: expected changes %v, got %v", test.name, test.expected, fields)
+		}
+	}
+}
+
+func TestCycle(t *testing.T) {
+	var version atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "<html><title>Version %d</title></html>", version.Load())
+	}))
+	defer server.Close()
+
+	sink := &recordingSink{}
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	config := &Config{URLs: []string{server.URL}, Threads: 2, Timeout: 5, StateFile: stateFile, Sinks: []Sink{sink}}
+
+	m, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Cycle(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.events) != 0 {
+		t.Fatalf("expected no events for the baseline, got %+v", sink.events)
+	}
+
+	// a new monitor picks the baseline up from the state file
+	version.Store(1)
+	m, err = New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Cycle(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var fields []string
+	for _, event := range sink.events {
+		fields = append(fields, event.Field)
+	}
+	if !slices.Equal(fields, []string{"title", "content_hash"}) {
+		t.Fatalf("expected title and content_hash events, got %+v", sink.events)
+	}
+	if event := sink.events[0]; event.Old != "Version 0" || event.New != "Version 1" || event.URL != server.URL {
+		t.Errorf("unexpected title event %+v", event)
+	}
+}
+
+func TestCycleCancelled(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer server.Close()
+
+	// enough URLs for the workers to start waiting on them before the loop could finish
+	var urls []string
+	for i := range 100000 {
+		urls = append(urls, fmt.Sprintf("%s/%d", server.URL, i))
+	}
+	config := &Config{URLs: urls, Threads: 2, Timeout: 5, StateFile: filepath.Join(t.TempDir(), "state.json")}
+	m, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := m.Cycle(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := requests.Load(); got != 0 {
+		t.Errorf("expected no URL to be probed after cancellation, got %d requests", got)
+	}
+}
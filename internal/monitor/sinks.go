@@ -0,0 +1,94 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// Sink receives the change events of a monitor
+type Sink interface {
+	Send(event Event) error
+}
+
+// WriterSink writes events as JSON lines, to stdout or to a file
+type WriterSink struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+func NewWriterSink(writer io.Writer) *WriterSink {
+	return &WriterSink{writer: writer}
+}
+
+// NewFileSink appends events to filename as JSON lines
+func NewFileSink(filename string) (*WriterSink, error) {
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening events file %s: %w", filename, err)
+	}
+	return NewWriterSink(file), nil
+}
+
+func (s *WriterSink) Send(event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.writer.Write(append(line, '\n'))
+	return err
+}
+
+// WebhookSink posts every event as JSON to a URL
+type WebhookSink struct {
+	URL    string
+	client *http.Client
+}
+
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{URL: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (s *WebhookSink) Send(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.client.Post(s.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s answered %s", s.URL, resp.Status)
+	}
+	return nil
+}
+
+// CommandSink runs a shell command for every event, with the event as JSON on its stdin
+type CommandSink struct {
+	Command string
+}
+
+func (s *CommandSink) Send(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command("sh", "-c", s.Command)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
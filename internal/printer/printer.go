@@ -2,6 +2,7 @@ package printer
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
@@ -9,10 +10,11 @@ import (
 
 	"github.com/GraveSIN/http-probe/internal/dnsprobe"
 	"github.com/GraveSIN/http-probe/internal/probe"
+	"github.com/GraveSIN/http-probe/internal/secheaders"
 	"github.com/fatih/color"
 )
 
-func StreamProbeResults(results chan probe.ProbeResult, outputFile string) {
+func StreamProbeResults(results chan probe.ProbeResult, outputFile string, jsonOutput, showCookies bool) {
 	var f *os.File
 	var writer *bufio.Writer
 
@@ -32,9 +34,37 @@ func StreamProbeResults(results chan probe.ProbeResult, outputFile string) {
 	greenStatus := color.New(color.FgGreen).SprintFunc()
 
 	for result := range results {
-		if result.StatusLine == "" {
+		// failures are written in --json output, so the file holds the full scan, and otherwise only
+		// reported for the addresses of --all-ips
+		if result.StatusLine == "" && result.Skipped == "" && (result.Error == "" || (!jsonOutput && result.DialAddress == "")) {
 			continue
 		}
+
+		if jsonOutput {
+			writeJSONLine(result, writer)
+			continue
+		}
+
+		if result.Error != "" {
+			output := fmt.Sprintf("[-] %s @ %s: %s\n", result.URL, result.DialAddress, redStatus(result.Error))
+			if outputFile != "" {
+				fmt.Fprint(writer, output)
+			} else {
+				fmt.Print(output)
+			}
+			continue
+		}
+
+		if result.Skipped != "" {
+			output := fmt.Sprintf("[-] %s: %s\n", result.URL, yellowStatus("skipped, "+result.Skipped))
+			if outputFile != "" {
+				fmt.Fprint(writer, output)
+			} else {
+				fmt.Print(output)
+			}
+			continue
+		}
+
 		var coloredStatus string
 		switch result.StatusLine[0] {
 		case '1':
@@ -52,8 +82,12 @@ func StreamProbeResults(results chan probe.ProbeResult, outputFile string) {
 		}
 
 		// Build output parts dynamically
+		url := result.URL
+		if result.DialAddress != "" {
+			url += " @ " + result.DialAddress
+		}
 		parts := []string{
-			fmt.Sprintf("[+] %s: [%s]", result.URL, coloredStatus),
+			fmt.Sprintf("[+] %s: [%s]", url, coloredStatus),
 		}
 
 		if result.RedirectLocation != "" {
@@ -79,11 +113,125 @@ func StreamProbeResults(results chan probe.ProbeResult, outputFile string) {
 		if result.PoweredByHeader != "" {
 			parts = append(parts, result.PoweredByHeader)
 		}
+		if result.Protocol != "" && result.Protocol != "HTTP/1.1" {
+			parts = append(parts, "["+result.Protocol+"]")
+		}
+		if len(result.Technologies) > 0 {
+			parts = append(parts, "["+strings.Join(result.Technologies, ", ")+"]")
+		}
+		if result.SecurityHeaders != nil {
+			parts = append(parts, fmt.Sprintf("[grade: %s]", result.SecurityHeaders.Grade))
+		}
+		if result.LikelySoft404 {
+			parts = append(parts, yellowStatus("[likely_soft_404]"))
+		}
+		if len(result.IPDisagreements) > 0 {
+			parts = append(parts, redStatus("[addresses disagree: "+strings.Join(result.IPDisagreements, ", ")+"]"))
+		}
+		switch result.IPv6Readiness {
+		case "":
+		case "ready":
+			parts = append(parts, greenStatus("[IPv6: ready]"))
+		case "unreachable":
+			parts = append(parts, redStatus("[IPv6: unreachable]"))
+		default:
+			parts = append(parts, yellowStatus("[IPv6: "+result.IPv6Readiness+"]"))
+		}
+		if result.DiscoveredFrom != "" {
+			parts = append(parts, "[via "+result.DiscoveredFrom+"]")
+		}
 		// time duration in ms
 		parts = append(parts, fmt.Sprintf("%dms", result.TimeTaken.Milliseconds()))
 
 		output := fmt.Sprintf("%s\n", strings.Join(parts, " "))
 
+		if result.SecurityHeaders != nil {
+			for _, finding := range result.SecurityHeaders.Findings {
+				if finding.Severity == secheaders.SeverityInfo {
+					continue
+				}
+				line := fmt.Sprintf("%s: %s (%s)", finding.Header, finding.Message, finding.Severity)
+				if finding.Severity == secheaders.SeverityHigh {
+					line = redStatus(line)
+				}
+				output += fmt.Sprintf("| %s\n", line)
+			}
+		}
+
+		if showCookies {
+			for _, cookie := range result.Cookies {
+				attributes := []string{fmt.Sprintf("%d bytes", cookie.Size)}
+				if cookie.Domain != "" {
+					attributes = append(attributes, "Domain="+cookie.Domain)
+				}
+				if cookie.Secure {
+					attributes = append(attributes, "Secure")
+				}
+				if cookie.HTTPOnly {
+					attributes = append(attributes, "HttpOnly")
+				}
+				if cookie.SameSite != "" {
+					attributes = append(attributes, "SameSite="+cookie.SameSite)
+				}
+				output += fmt.Sprintf("| Cookie %s: %s\n", cookie.Name, strings.Join(attributes, " "))
+				for _, issue := range cookie.Issues {
+					output += fmt.Sprintf("|   %s\n", yellowStatus(issue))
+				}
+			}
+		}
+
+		if fingerprint := result.TLSFingerprint; fingerprint != nil {
+			output += fmt.Sprintf("| JARM: %s\n", fingerprint.JARM)
+			if fingerprint.JA3S != "" {
+				output += fmt.Sprintf("| JA3S: %s (%s)\n", fingerprint.JA3S, fingerprint.JA3SRaw)
+			}
+		}
+
+		if tlsScan := result.TLSScan; tlsScan != nil {
+			output += fmt.Sprintf("| TLS grade %s: %s\n", tlsScan.Grade, strings.Join(tlsScan.Versions, ", "))
+			for _, version := range tlsScan.Versions {
+				output += fmt.Sprintf("|   %s: %s\n", version, strings.Join(tlsScan.CipherSuites[version], " "))
+			}
+			output += fmt.Sprintf("|   curves: %s, server cipher preference: %t, OCSP stapling: %t, session resumption: %t\n",
+				strings.Join(tlsScan.Curves, " "), tlsScan.ServerCipherPreference, tlsScan.OCSPStapling, tlsScan.SessionResumption)
+			for _, issue := range tlsScan.Issues {
+				output += fmt.Sprintf("|   %s\n", yellowStatus(issue))
+			}
+		}
+
+		for _, http3 := range result.HTTP3 {
+			line := fmt.Sprintf("HTTP/3 %s (%s): ", http3.Endpoint, http3.ALPN)
+			if http3.Success {
+				line += greenStatus(fmt.Sprintf("QUIC %s, handshake %dms", http3.QUICVersion, http3.HandshakeTime.Milliseconds()))
+			} else {
+				line += redStatus(http3.Error)
+			}
+			output += fmt.Sprintf("| %s\n", line)
+		}
+
+		for _, finding := range result.CORS {
+			line := fmt.Sprintf("CORS %s: Origin %s -> Access-Control-Allow-Origin: %s", finding.Test, finding.Origin, finding.AllowOrigin)
+			if finding.AllowCredentials {
+				line += " (with credentials)"
+			}
+			if finding.Severity == "high" {
+				line = redStatus(line)
+			}
+			output += fmt.Sprintf("| %s\n", line)
+		}
+
+		if result.Methods != nil {
+			if result.Methods.Allow != "" {
+				output += fmt.Sprintf("| Allow: %s\n", result.Methods.Allow)
+			}
+			if len(result.Methods.Accepted) > 0 {
+				output += fmt.Sprintf("| Methods: %s\n", strings.Join(result.Methods.Accepted, " "))
+			}
+			for _, finding := range result.Methods.Dangerous {
+				output += fmt.Sprintf("| %s\n", redStatus(finding))
+			}
+		}
+
 		if outputFile != "" {
 			fmt.Fprint(writer, output)
 		} else {
@@ -92,7 +240,7 @@ func StreamProbeResults(results chan probe.ProbeResult, outputFile string) {
 	}
 }
 
-func StreamDNSProbeResults(results chan dnsprobe.DNSProbeResult, outputFile string) {
+func StreamDNSProbeResults(results chan dnsprobe.DNSProbeResult, outputFile string, jsonOutput bool) {
 	var f *os.File
 	var writer *bufio.Writer
 
@@ -108,6 +256,10 @@ func StreamDNSProbeResults(results chan dnsprobe.DNSProbeResult, outputFile stri
 	}
 
 	for result := range results {
+		if jsonOutput {
+			writeJSONLine(result, writer)
+			continue
+		}
 
 		cyan := color.New(color.FgCyan).SprintFunc()
 		blue := color.New(color.FgBlue).SprintFunc()
@@ -131,6 +283,13 @@ func StreamDNSProbeResults(results chan dnsprobe.DNSProbeResult, outputFile stri
 		if len(result.TXTRecords) > 0 {
 			output += fmt.Sprintf("| "+blue("TXT")+":\n|   %v\n", strings.Join(result.TXTRecords, "\n|   "))
 		}
+		if result.DualStack != nil {
+			dualStack := "yes"
+			if !*result.DualStack {
+				dualStack = "no"
+			}
+			output += "| " + blue("Dual-stack") + ": " + dualStack + "\n"
+		}
 
 		if outputFile != "" {
 			fmt.Fprint(writer, output)
@@ -139,3 +298,18 @@ func StreamDNSProbeResults(results chan dnsprobe.DNSProbeResult, outputFile stri
 		}
 	}
 }
+
+// writeJSONLine writes the result as a single JSON line to the writer, or to stdout when writer is nil
+func writeJSONLine(result any, writer *bufio.Writer) {
+	line, err := json.Marshal(result)
+	if err != nil {
+		log.Printf("[!] Failed to encode result: %v", err)
+		return
+	}
+
+	if writer != nil {
+		writer.Write(append(line, '\n'))
+	} else {
+		fmt.Println(string(line))
+	}
+}
@@ -0,0 +1,98 @@
+package probe
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/url"
+
+	"github.com/valyala/fasthttp"
+)
+
+// probeAllIPs probes target once per address its host resolves to, with the host kept for the
+// Host header and SNI, and flags the results when the addresses disagree. Addresses that don't
+// answer are reported with their error.
+func (p *Prober) probeAllIPs(target probeTarget, req *fasthttp.Request, resp *fasthttp.Response) []ProbeResult {
+	parsed, err := url.Parse(target.URL)
+	if err != nil || target.DialAddr != "" || net.ParseIP(parsed.Hostname()) != nil {
+		return []ProbeResult{p.probeURL(target, req, resp)}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout())
+	addrs, err := p.lookupAddr(ctx, fasthttp.AddMissingPort(parsed.Host, parsed.Scheme == "https"))
+	cancel()
+	if err != nil {
+		return []ProbeResult{failedResult(target, err)}
+	}
+
+	var results []ProbeResult
+	for _, addr := range addrs {
+		pinned := target
+		pinned.DialAddr = addr.IP.String()
+		result := p.probeURL(pinned, req, resp)
+		if result.StatusLine == "" && result.Skipped == "" && result.Error == "" {
+			// results dropped on purpose stay dropped
+			continue
+		}
+		result.DialAddress = pinned.DialAddr
+		if parsed.Scheme == "https" && result.StatusLine != "" && result.CertificateSHA256 == "" {
+			port := parsed.Port()
+			if port == "" {
+				port = "443"
+			}
+			result.CertificateSHA256 = p.certificateFingerprint(net.JoinHostPort(pinned.DialAddr, port), parsed.Hostname())
+		}
+		results = append(results, result)
+	}
+
+	disagreements := ipDisagreements(results)
+	for i := range results {
+		results[i].IPDisagreements = disagreements
+	}
+	return results
+}
+
+// ipDisagreements lists the fields (status, title, certificate) that differ between the results of one host
+func ipDisagreements(results []ProbeResult) []string {
+	var disagreements []string
+	differs := func(field func(ProbeResult) string) bool {
+		var first *string
+		for _, result := range results {
+			if result.StatusLine == "" {
+				continue
+			}
+			value := field(result)
+			if first == nil {
+				first = &value
+			} else if value != *first {
+				return true
+			}
+		}
+		return false
+	}
+
+	if differs(func(r ProbeResult) string { return r.StatusLine }) {
+		disagreements = append(disagreements, "status")
+	}
+	if differs(func(r ProbeResult) string { return r.Title }) {
+		disagreements = append(disagreements, "title")
+	}
+	if differs(func(r ProbeResult) string { return r.CertificateSHA256 }) {
+		disagreements = append(disagreements, "certificate")
+	}
+	return disagreements
+}
+
+// certificateFingerprint returns the SHA-256 of the certificate served at addr for serverName
+func (p *Prober) certificateFingerprint(addr, serverName string) string {
+	tlsConfig := p.client.TLSConfig.Clone()
+	tlsConfig.ServerName = serverName
+
+	state, ok := p.handshakeWithConfig(addr, tlsConfig)
+	if !ok || len(state.PeerCertificates) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256(state.PeerCertificates[0].Raw)
+	return hex.EncodeToString(sum[:])
+}
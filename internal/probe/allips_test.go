@@ -0,0 +1,130 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/valyala/fasthttp"
+)
+
+type staticResolver []net.IPAddr
+
+func (r staticResolver) LookupIPAddr(context.Context, string) ([]net.IPAddr, error) {
+	return r, nil
+}
+
+func TestProbeAllIPs(t *testing.T) {
+	first, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, _ := net.SplitHostPort(first.Addr().String())
+	second, err := net.Listen("tcp", "127.0.0.2:"+port)
+	if err != nil {
+		first.Close()
+		t.Skipf("127.0.0.2 is not available: %v", err)
+	}
+
+	for i, listener := range []net.Listener{first, second} {
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "<html><title>Backend %d</title></html>", i)
+		}))
+		server.Listener = listener
+		server.Config.ErrorLog = log.New(io.Discard, "", 0)
+		server.StartTLS()
+		defer server.Close()
+	}
+
+	prober := NewProber(&ProberConfig{URLs: &[]string{}, Threads: 1, Timeout: 5, Method: "GET", AllIPs: true})
+	prober.resolver = staticResolver{{IP: net.ParseIP("127.0.0.1")}, {IP: net.ParseIP("127.0.0.2")}}
+
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	results := prober.probeAllIPs(probeTarget{URL: "https://backend.example.test:" + port}, req, resp)
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d: %+v", len(results), results)
+	}
+
+	for i, result := range results {
+		if expected := fmt.Sprintf("127.0.0.%d", i+1); result.DialAddress != expected {
+			t.Errorf("expected dial address %s, got %s", expected, result.DialAddress)
+		}
+		if result.CertificateSHA256 == "" {
+			t.Errorf("expected a certificate fingerprint for %s", result.DialAddress)
+		}
+		if !slices.Equal(result.IPDisagreements, []string{"title"}) {
+			t.Errorf("expected the titles to disagree, got %v", result.IPDisagreements)
+		}
+	}
+}
+
+func TestProbeAllIPsReportsEveryAddress(t *testing.T) {
+	ipv4Listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, _ := net.SplitHostPort(ipv4Listener.Addr().String())
+	ipv6Listener, err := net.Listen("tcp", "[::1]:"+port)
+	if err != nil {
+		ipv4Listener.Close()
+		t.Skipf("::1 is not available: %v", err)
+	}
+
+	for _, listener := range []net.Listener{ipv4Listener, ipv6Listener} {
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "<html><title>Backend</title></html>")
+		}))
+		server.Listener = listener
+		server.Config.ErrorLog = log.New(io.Discard, "", 0)
+		server.Start()
+		defer server.Close()
+	}
+
+	// IPv6 addresses are dialed without -6 or --dual-stack, and nothing listens on 127.0.0.3
+	prober := NewProber(&ProberConfig{URLs: &[]string{}, Threads: 1, Timeout: 5, Method: "GET", AllIPs: true})
+	prober.resolver = staticResolver{{IP: net.ParseIP("127.0.0.1")}, {IP: net.ParseIP("::1")}, {IP: net.ParseIP("127.0.0.3")}}
+
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	results := prober.probeAllIPs(probeTarget{URL: "http://backend.example.test:" + port}, req, resp)
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d: %+v", len(results), results)
+	}
+	for _, result := range results[:2] {
+		if result.Title != "Backend" || result.Error != "" {
+			t.Errorf("expected %s to answer, got %+v", result.DialAddress, result)
+		}
+	}
+	if failed := results[2]; failed.DialAddress != "127.0.0.3" || failed.Error == "" || failed.StatusLine != "" {
+		t.Errorf("expected 127.0.0.3 to be reported with an error, got %+v", failed)
+	}
+	if len(results[0].IPDisagreements) != 0 {
+		t.Errorf("expected failed addresses to be left out of disagreements, got %v", results[0].IPDisagreements)
+	}
+}
+
+func TestAllIPsRejectsDualStack(t *testing.T) {
+	cmd := &cobra.Command{}
+	cmd.Flags().StringSlice("url", []string{"example.com"}, "")
+	cmd.Flags().Bool("all-ips", true, "")
+	cmd.Flags().Bool("dual-stack", true, "")
+
+	if _, err := ParseHTTPProbeConfig(cmd); err == nil || !strings.Contains(err.Error(), "--all-ips and --dual-stack") {
+		t.Errorf("expected --all-ips with --dual-stack to be rejected, got %v", err)
+	}
+}
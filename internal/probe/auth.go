@@ -0,0 +1,309 @@
+package probe
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"os"
+	"strings"
+
+	"github.com/GraveSIN/http-probe/internal/utils"
+	"github.com/valyala/fasthttp"
+)
+
+// Authentication schemes
+const (
+	authBasic  = "basic"
+	authBearer = "bearer"
+	authDigest = "digest"
+	authNTLM   = "ntlm"
+)
+
+// Credentials holds what is needed to authenticate against a host
+type Credentials struct {
+	Scheme   string
+	Username string
+	Password string
+	// Domain is only used by NTLM, given as DOMAIN\user
+	Domain string
+	Token  string
+}
+
+// hostCredentials maps host patterns to credentials, with *.example.com matching any subdomain
+// and *example.com matching example.com too
+type hostCredentials struct {
+	pattern     string
+	credentials *Credentials
+}
+
+// ParseCredentials parses an --auth value: basic:user:pass, digest:user:pass, ntlm:DOMAIN\user:pass or bearer:token
+func ParseCredentials(value string) (*Credentials, error) {
+	scheme, rest, found := strings.Cut(value, ":")
+	if !found {
+		return nil, fmt.Errorf("invalid auth %q, expected scheme:credentials", value)
+	}
+
+	scheme = strings.ToLower(scheme)
+	if scheme == authBearer {
+		return &Credentials{Scheme: authBearer, Token: rest}, nil
+	}
+
+	username, password, found := strings.Cut(rest, ":")
+	if !found {
+		return nil, fmt.Errorf("invalid auth %q, expected %s:user:pass", value, scheme)
+	}
+
+	switch scheme {
+	case authBasic, authDigest:
+		return &Credentials{Scheme: scheme, Username: username, Password: password}, nil
+	case authNTLM:
+		credentials := &Credentials{Scheme: authNTLM, Username: username, Password: password}
+		if domain, user, found := strings.Cut(username, `\`); found {
+			credentials.Domain = domain
+			credentials.Username = user
+		}
+		return credentials, nil
+	default:
+		return nil, fmt.Errorf("unsupported auth scheme %q", scheme)
+	}
+}
+
+// readCredentialsFile reads per-host credentials, one "host scheme:credentials" entry per line
+func readCredentialsFile(file string) ([]hostCredentials, error) {
+	lines, err := utils.ReadURLsFromFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]hostCredentials, 0, len(lines))
+	for _, line := range lines {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid credentials line %q, expected: host scheme:credentials", line)
+		}
+
+		credentials, err := ParseCredentials(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, hostCredentials{pattern: strings.ToLower(fields[0]), credentials: credentials})
+	}
+
+	return entries, nil
+}
+
+// credentialsFor returns the credentials for the host of target, falling back to the global ones.
+// Hosts found by --discover and --vhost wordlist hostnames only get the credentials of their --auth-file
+// entry, as the global ones are meant for the input targets.
+func (p *Prober) credentialsFor(target probeTarget, host string) *Credentials {
+	host = strings.ToLower(host)
+	hostname := host
+	if idx := strings.LastIndex(host, ":"); idx >= 0 && !strings.HasSuffix(host, "]") {
+		hostname = host[:idx]
+	}
+
+	for _, entry := range p.config.HostCredentials {
+		if entry.pattern == host || entry.pattern == hostname || matchesWildcard(entry.pattern, hostname) {
+			return entry.credentials
+		}
+	}
+
+	if target.DiscoveredFrom != "" || len(p.config.VHosts) > 0 {
+		return nil
+	}
+	return p.config.Credentials
+}
+
+// matchesWildcard reports whether hostname is a subdomain of a *.example.com pattern, or is example.com
+// or one of its subdomains for a *example.com pattern. Other hosts ending with the same characters,
+// like evilexample.com, never match.
+func matchesWildcard(pattern, hostname string) bool {
+	suffix, found := strings.CutPrefix(pattern, "*")
+	if !found {
+		return false
+	}
+	domain := strings.TrimPrefix(suffix, ".")
+	if domain == "" {
+		return false
+	}
+	return strings.HasSuffix(hostname, "."+domain) || (!strings.HasPrefix(suffix, ".") && hostname == domain)
+}
+
+// setAuthorization sets the Authorization header for schemes that don't need a challenge
+func setAuthorization(req *fasthttp.Request, credentials *Credentials) {
+	switch credentials.Scheme {
+	case authBasic:
+		token := base64.StdEncoding.EncodeToString([]byte(credentials.Username + ":" + credentials.Password))
+		req.Header.Set("Authorization", "Basic "+token)
+	case authBearer:
+		req.Header.Set("Authorization", "Bearer "+credentials.Token)
+	}
+}
+
+// answerDigestChallenge sets the Authorization header for a Digest challenge from a 401 response.
+// It returns false when the response carries no Digest challenge.
+func answerDigestChallenge(req *fasthttp.Request, resp *fasthttp.Response, credentials *Credentials) bool {
+	challenge := findChallenge(resp, "Digest")
+	if challenge == "" {
+		return false
+	}
+
+	uri := string(req.URI().RequestURI())
+	header, ok := digestAuthorization(string(req.Header.Method()), uri, parseChallengeParams(challenge), credentials, randomToken(8))
+	if !ok {
+		return false
+	}
+	req.Header.Set("Authorization", header)
+	return true
+}
+
+// digestAuthorization computes the Authorization header answering a Digest challenge (RFC 7616) for a
+// request, with cnonce as the client nonce. It returns false for unsupported algorithms.
+func digestAuthorization(method, uri string, params map[string]string, credentials *Credentials, cnonce string) (string, bool) {
+	var newHash func() hash.Hash
+	algorithm := params["algorithm"]
+	switch strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS") {
+	case "", "MD5":
+		newHash = md5.New
+	case "SHA-256":
+		newHash = sha256.New
+	default:
+		return "", false
+	}
+	digest := func(parts ...string) string {
+		h := newHash()
+		h.Write([]byte(strings.Join(parts, ":")))
+		return hex.EncodeToString(h.Sum(nil))
+	}
+
+	realm, nonce := params["realm"], params["nonce"]
+	nc := "00000001"
+
+	ha1 := digest(credentials.Username, realm, credentials.Password)
+	if strings.HasSuffix(strings.ToUpper(algorithm), "-SESS") {
+		ha1 = digest(ha1, nonce, cnonce)
+	}
+	ha2 := digest(method, uri)
+
+	header := fmt.Sprintf(`Digest username="%s", realm="%s", nonce="%s", uri="%s"`, credentials.Username, realm, nonce, uri)
+	if qops := params["qop"]; qops != "" {
+		header += fmt.Sprintf(`, qop=auth, nc=%s, cnonce="%s", response="%s"`, nc, cnonce, digest(ha1, nonce, nc, cnonce, "auth", ha2))
+	} else {
+		header += fmt.Sprintf(`, response="%s"`, digest(ha1, nonce, ha2))
+	}
+	if algorithm != "" {
+		header += ", algorithm=" + algorithm
+	}
+	if opaque := params["opaque"]; opaque != "" {
+		header += fmt.Sprintf(`, opaque="%s"`, opaque)
+	}
+	return header, true
+}
+
+// findChallenge returns the parameters of the first WWW-Authenticate challenge for the scheme
+func findChallenge(resp *fasthttp.Response, scheme string) string {
+	for _, value := range resp.Header.PeekAll("WWW-Authenticate") {
+		challenge := string(value)
+		if len(challenge) >= len(scheme) && strings.EqualFold(challenge[:len(scheme)], scheme) {
+			return strings.TrimSpace(challenge[len(scheme):])
+		}
+	}
+	return ""
+}
+
+// parseChallengeParams parses comma separated key=value pairs, with optionally quoted values
+func parseChallengeParams(challenge string) map[string]string {
+	params := make(map[string]string)
+	for challenge != "" {
+		key, rest, found := strings.Cut(challenge, "=")
+		if !found {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		rest = strings.TrimSpace(rest)
+
+		var value string
+		if quoted, found := strings.CutPrefix(rest, `"`); found {
+			value, rest, _ = strings.Cut(quoted, `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+			value = strings.TrimSpace(value)
+			rest = "," + rest
+		}
+		params[key] = value
+
+		_, challenge, _ = strings.Cut(rest, ",")
+		challenge = strings.TrimSpace(challenge)
+	}
+	return params
+}
+
+// loadClientTLS loads the client certificate and CA bundle given with --cert, --key and --cacert
+func loadClientTLS(certFile, keyFile, caFile string) (*tls.Certificate, *x509.CertPool, error) {
+	var certificate *tls.Certificate
+	if certFile != "" || keyFile != "" {
+		if keyFile == "" {
+			// allow a single PEM file holding both the certificate and the key
+			keyFile = certFile
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error loading client certificate: %w", err)
+		}
+		certificate = &cert
+	}
+
+	var rootCAs *x509.CertPool
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading CA file %s: %w", caFile, err)
+		}
+		rootCAs = x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(pem) {
+			return nil, nil, fmt.Errorf("no certificates found in CA file %s", caFile)
+		}
+	}
+
+	return certificate, rootCAs, nil
+}
+
+// authenticate answers a 401 challenge for the challenge-response schemes, replacing resp
+// doAuthenticatedRequest sends req with credentials, answering the Digest or NTLM challenge of a 401
+// like the probe does. Without credentials, req is sent as it is.
+func (p *Prober) doAuthenticatedRequest(pinned string, credentials *Credentials, req *fasthttp.Request, resp *fasthttp.Response) error {
+	if credentials == nil {
+		return p.doRequest(pinned, req, resp)
+	}
+
+	setAuthorization(req, credentials)
+	if err := p.doRequest(pinned, req, resp); err != nil {
+		return err
+	}
+	if resp.StatusCode() == fasthttp.StatusUnauthorized {
+		return p.authenticate(req, resp, credentials, pinned)
+	}
+	return nil
+}
+
+func (p *Prober) authenticate(req *fasthttp.Request, resp *fasthttp.Response, credentials *Credentials, pinned string) error {
+	switch credentials.Scheme {
+	case authDigest:
+		if !answerDigestChallenge(req, resp, credentials) {
+			return nil
+		}
+		return p.doRequest(pinned, req, resp)
+	case authNTLM:
+		return p.doNTLMRequest(req, resp, credentials, pinned)
+	}
+	return nil
+}
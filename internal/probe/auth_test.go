@@ -0,0 +1,267 @@
+package probe
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseCredentials(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected Credentials
+		wantErr  bool
+	}{
+		{
+			name:     "Basic with colon in password",
+			input:    "basic:admin:pa:ss",
+			expected: Credentials{Scheme: authBasic, Username: "admin", Password: "pa:ss"},
+		},
+		{
+			name:     "Bearer",
+			input:    "bearer:abc.def",
+			expected: Credentials{Scheme: authBearer, Token: "abc.def"},
+		},
+		{
+			name:     "NTLM with domain",
+			input:    `ntlm:CORP\svc:secret`,
+			expected: Credentials{Scheme: authNTLM, Domain: "CORP", Username: "svc", Password: "secret"},
+		},
+		{
+			name:    "Unknown scheme",
+			input:   "kerberos:user:pass",
+			wantErr: true,
+		},
+		{
+			name:    "Missing password",
+			input:   "basic:admin",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			credentials, err := ParseCredentials(tc.input)
+
+			if tc.wantErr {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			if *credentials != tc.expected {
+				t.Errorf("expected %+v, got %+v", tc.expected, *credentials)
+			}
+		})
+	}
+}
+
+func TestParseChallengeParams(t *testing.T) {
+	params := parseChallengeParams(`realm="testrealm@host.com", qop="auth,auth-int", nonce="dcd98b7102dd2f0e8b11d0f600bfb0c093", algorithm=MD5, opaque="5ccc069c403ebaf9f0171e9517f40e41"`)
+
+	expected := map[string]string{
+		"realm":     "testrealm@host.com",
+		"qop":       "auth,auth-int",
+		"nonce":     "dcd98b7102dd2f0e8b11d0f600bfb0c093",
+		"algorithm": "MD5",
+		"opaque":    "5ccc069c403ebaf9f0171e9517f40e41",
+	}
+
+	for key, value := range expected {
+		if params[key] != value {
+			t.Errorf("expected %s=%s, got %s", key, value, params[key])
+		}
+	}
+}
+
+func TestCredentialsFor(t *testing.T) {
+	global := &Credentials{Scheme: authBearer, Token: "global"}
+	internal := &Credentials{Scheme: authBasic, Username: "internal"}
+	apex := &Credentials{Scheme: authBasic, Username: "apex"}
+	prober := &Prober{config: &ProberConfig{
+		Credentials: global,
+		HostCredentials: []hostCredentials{
+			{pattern: "*.internal.corp", credentials: internal},
+			{pattern: "*example.com", credentials: apex},
+		},
+	}}
+
+	testCases := []struct {
+		name     string
+		target   probeTarget
+		host     string
+		expected *Credentials
+	}{
+		{"Subdomain wildcard", probeTarget{}, "wiki.internal.corp:8443", internal},
+		{"Subdomain wildcard excludes the apex", probeTarget{}, "internal.corp", global},
+		{"Apex wildcard", probeTarget{}, "example.com", apex},
+		{"Apex wildcard subdomain", probeTarget{}, "www.example.com", apex},
+		{"Same suffix on another domain", probeTarget{}, "evilexample.com", global},
+		{"Discovered host without an entry", probeTarget{DiscoveredFrom: "https://example.org"}, "cdn.example.org", nil},
+		{"Discovered host with an entry", probeTarget{DiscoveredFrom: "https://example.org"}, "api.example.com", apex},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if credentials := prober.credentialsFor(tc.target, tc.host); credentials != tc.expected {
+				t.Errorf("expected %+v, got %+v", tc.expected, credentials)
+			}
+		})
+	}
+}
+
+func TestDigestAuthorization(t *testing.T) {
+	testCases := []struct {
+		name        string
+		challenge   string
+		credentials Credentials
+		cnonce      string
+		expected    string
+	}{
+		{
+			// RFC 2617 section 3.5
+			name:        "MD5",
+			challenge:   `realm="testrealm@host.com", qop="auth,auth-int", nonce="dcd98b7102dd2f0e8b11d0f600bfb0c093", opaque="5ccc069c403ebaf9f0171e9517f40e41"`,
+			credentials: Credentials{Scheme: authDigest, Username: "Mufasa", Password: "Circle Of Life"},
+			cnonce:      "0a4f113b",
+			expected:    "6629fae49393a05397450978507c4ef1",
+		},
+		{
+			// RFC 7616 section 3.9.1
+			name:        "SHA-256",
+			challenge:   `realm="http-auth@example.org", qop="auth, auth-int", algorithm=SHA-256, nonce="7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v", opaque="FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS"`,
+			credentials: Credentials{Scheme: authDigest, Username: "Mufasa", Password: "Circle of Life"},
+			cnonce:      "f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ",
+			expected:    "753927fa0e85d155564e2e272a28d1802ca10daf4496794697cf8db5856cb6c1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			header, ok := digestAuthorization("GET", "/dir/index.html", parseChallengeParams(tc.challenge), &tc.credentials, tc.cnonce)
+			if !ok {
+				t.Fatal("expected the challenge to be answered")
+			}
+			params := parseChallengeParams(strings.TrimPrefix(header, "Digest "))
+			if params["response"] != tc.expected {
+				t.Errorf("expected response %s, got %s in %s", tc.expected, params["response"], header)
+			}
+			if params["username"] != "Mufasa" || params["uri"] != "/dir/index.html" || params["cnonce"] != tc.cnonce {
+				t.Errorf("unexpected header %s", header)
+			}
+		})
+	}
+}
+
+func TestNoCleartextCredentialsFallback(t *testing.T) {
+	// a plain HTTP server makes the TLS handshake fail, like anything on the path could
+	var mu sync.Mutex
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, r.Header.Get("Authorization"))
+	}))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	defer server.Close()
+	httpsURL := "https://" + strings.TrimPrefix(server.URL, "http://") + "/"
+
+	basic := &Credentials{Scheme: authBasic, Username: "admin", Password: "s3cret"}
+	testCases := []struct {
+		name     string
+		config   ProberConfig
+		expected []string
+	}{
+		{"Basic credentials", ProberConfig{Credentials: basic}, nil},
+		{"Basic credentials over HTTP/2", ProberConfig{Credentials: basic, HTTP2: true}, nil},
+		{"No credentials", ProberConfig{}, []string{""}},
+		{"Opted in", ProberConfig{Credentials: basic, AuthHTTPFallback: true}, []string{"Basic YWRtaW46czNjcmV0"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			received = nil
+			config := tc.config
+			config.URLs, config.Threads, config.Timeout, config.Method = &[]string{}, 1, 5, "GET"
+			results := NewProber(&config).Probe(httpsURL)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if !slices.Equal(received, tc.expected) {
+				t.Errorf("expected Authorization headers %q over HTTP, got %q", tc.expected, received)
+			}
+			if tc.expected != nil && (len(results) != 1 || results[0].URL != server.URL+"/") {
+				t.Errorf("expected the result to report the plain HTTP URL, got %+v", results)
+			}
+		})
+	}
+}
+
+func TestAuthenticatedChecks(t *testing.T) {
+	// behind the login wall every unknown path serves the same page, and origins are only trusted
+	// for authenticated requests
+	var mu sync.Mutex
+	var unauthenticated []string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "admin" || password != "s3cret" {
+			mu.Lock()
+			unauthenticated = append(unauthenticated, r.Method+" "+r.URL.Path)
+			mu.Unlock()
+			w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			io.WriteString(w, "<html><title>Login</title></html>")
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+		io.WriteString(w, "<html><title>Dashboard</title><body>nothing here</body></html>")
+	}))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	defer server.Close()
+
+	prober := NewProber(&ProberConfig{
+		URLs:        &[]string{},
+		Threads:     1,
+		Timeout:     5,
+		Method:      "GET",
+		Credentials: &Credentials{Scheme: authBasic, Username: "admin", Password: "s3cret"},
+		Soft404:     true,
+		CORS:        true,
+		MethodsScan: true,
+	})
+	results := prober.Probe(server.URL + "/missing")
+	if len(results) != 1 || results[0].StatusCode != http.StatusOK {
+		t.Fatalf("expected an authenticated 200, got %+v", results)
+	}
+	result := results[0]
+
+	if !result.LikelySoft404 {
+		t.Error("expected the baseline to be built behind the credentials and match")
+	}
+	if !slices.ContainsFunc(result.CORS, func(finding CORSFinding) bool { return finding.Severity == "high" }) {
+		t.Errorf("expected the credentialed reflection to be found, got %+v", result.CORS)
+	}
+	if result.Methods == nil || result.Methods.Statuses["GET"] != http.StatusOK || result.Methods.Statuses["OPTIONS"] != http.StatusOK {
+		t.Errorf("expected the methods to be scanned behind the credentials, got %+v", result.Methods)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(unauthenticated) > 0 {
+		t.Errorf("expected every request to be authenticated, got unauthenticated %v", unauthenticated)
+	}
+}
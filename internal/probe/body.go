@@ -0,0 +1,144 @@
+package probe
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// FormField is a single multipart form field given with -F
+type FormField struct {
+	Name string
+	// Value is used for plain fields
+	Value string
+	// FileName and Content are set for file uploads given as name=@path
+	FileName string
+	Content  []byte
+}
+
+// readBodyArgument returns the argument itself, or the contents of the file when it starts with '@'
+func readBodyArgument(arg string) (string, error) {
+	if !strings.HasPrefix(arg, "@") {
+		return arg, nil
+	}
+
+	content, err := os.ReadFile(arg[1:])
+	if err != nil {
+		return "", fmt.Errorf("error reading body file %s: %w", arg[1:], err)
+	}
+	return string(content), nil
+}
+
+// parseFormFields parses -F arguments of the form name=value or name=@path
+func parseFormFields(args []string) ([]FormField, error) {
+	fields := make([]FormField, 0, len(args))
+	for _, arg := range args {
+		name, value, found := strings.Cut(arg, "=")
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid form field %q, expected name=value or name=@file", arg)
+		}
+
+		if !strings.HasPrefix(value, "@") {
+			fields = append(fields, FormField{Name: name, Value: value})
+			continue
+		}
+
+		content, err := os.ReadFile(value[1:])
+		if err != nil {
+			return nil, fmt.Errorf("error reading form file %s: %w", value[1:], err)
+		}
+		fields = append(fields, FormField{
+			Name:     name,
+			FileName: filepath.Base(value[1:]),
+			Content:  content,
+		})
+	}
+	return fields, nil
+}
+
+// setRequestBody sets the configured body on the request, substituting template variables for the target
+// except in --data-binary bodies, which are sent as-is
+func (p *Prober) setRequestBody(req *fasthttp.Request, url string) error {
+	var body []byte
+	var bodyContentType string
+
+	switch {
+	case len(p.config.Form) > 0:
+		var err error
+		body, bodyContentType, err = buildMultipartBody(p.config.Form, url)
+		if err != nil {
+			return err
+		}
+	case p.config.BodyBinary:
+		body = []byte(p.config.Body)
+		bodyContentType = "application/octet-stream"
+	case p.config.Body != "":
+		body = []byte(expandTemplate(p.config.Body, url))
+		bodyContentType = detectContentType(body[0], string(body))
+	default:
+		return nil
+	}
+
+	if p.config.ContentType != "" {
+		bodyContentType = p.config.ContentType
+	}
+
+	req.SetBody(body)
+	req.Header.SetContentLength(len(body))
+	req.Header.Set("Content-Type", bodyContentType)
+	return nil
+}
+
+// buildMultipartBody encodes the form fields as multipart/form-data
+func buildMultipartBody(fields []FormField, url string) ([]byte, string, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	for _, field := range fields {
+		if field.FileName == "" {
+			if err := writer.WriteField(field.Name, expandTemplate(field.Value, url)); err != nil {
+				return nil, "", err
+			}
+			continue
+		}
+
+		part, err := writer.CreateFormFile(field.Name, field.FileName)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := part.Write(field.Content); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), writer.FormDataContentType(), nil
+}
+
+// expandTemplate substitutes {{host}}, {{url}} and {{random}} for the given target URL
+func expandTemplate(body, url string) string {
+	if !strings.Contains(body, "{{") {
+		return body
+	}
+
+	host := url
+	if idx := strings.Index(host, "://"); idx >= 0 {
+		host = host[idx+3:]
+	}
+	if idx := strings.IndexAny(host, "/?#"); idx >= 0 {
+		host = host[:idx]
+	}
+
+	return strings.NewReplacer(
+		"{{host}}", host,
+		"{{url}}", url,
+		"{{random}}", randomToken(8),
+	).Replace(body)
+}
@@ -0,0 +1,79 @@
+package probe
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestExpandTemplate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		body     string
+		url      string
+		expected string
+	}{
+		{
+			name:     "No placeholders",
+			body:     `{"a":1}`,
+			url:      "https://example.com/api",
+			expected: `{"a":1}`,
+		},
+		{
+			name:     "Host and URL",
+			body:     `{"host":"{{host}}","url":"{{url}}"}`,
+			url:      "https://example.com:8443/api?x=1",
+			expected: `{"host":"example.com:8443","url":"https://example.com:8443/api?x=1"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := expandTemplate(tc.body, tc.url); result != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, result)
+			}
+		})
+	}
+
+	t.Run("Random", func(t *testing.T) {
+		result := expandTemplate("id={{random}}", "https://example.com")
+		if !strings.HasPrefix(result, "id=") || len(result) != len("id=")+16 {
+			t.Errorf("unexpected random substitution: %s", result)
+		}
+	})
+}
+
+func TestSetRequestBody(t *testing.T) {
+	testCases := []struct {
+		name     string
+		config   ProberConfig
+		expected string
+	}{
+		{
+			name:     "Templated data",
+			config:   ProberConfig{Body: `{"url":"{{url}}"}`},
+			expected: `{"url":"https://example.com/api"}`,
+		},
+		{
+			name:     "Binary data sent as-is",
+			config:   ProberConfig{Body: "\x00{{url}}\xff", BodyBinary: true},
+			expected: "\x00{{url}}\xff",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prober := &Prober{config: &tc.config}
+			req := fasthttp.AcquireRequest()
+			defer fasthttp.ReleaseRequest(req)
+
+			if err := prober.setRequestBody(req, "https://example.com/api"); err != nil {
+				t.Fatal(err)
+			}
+			if body := string(req.Body()); body != tc.expected {
+				t.Errorf("expected body %q, got %q", tc.expected, body)
+			}
+		})
+	}
+}
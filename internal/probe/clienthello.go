@@ -0,0 +1,289 @@
+package probe
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"time"
+)
+
+// TLS record and handshake types used when speaking TLS by hand
+const (
+	recordTypeAlert          = 21
+	recordTypeHandshake      = 22
+	handshakeTypeClientHello = 1
+	handshakeTypeServerHello = 2
+)
+
+// TLS extension types written into crafted ClientHellos
+const (
+	extensionServerName           = 0x0000
+	extensionMaxFragmentLength    = 0x0001
+	extensionSupportedGroups      = 0x000a
+	extensionECPointFormats       = 0x000b
+	extensionSignatureAlgorithms  = 0x000d
+	extensionALPN                 = 0x0010
+	extensionExtendedMasterSecret = 0x0017
+	extensionSessionTicket        = 0x0023
+	extensionSupportedVersions    = 0x002b
+	extensionPSKKeyExchangeModes  = 0x002d
+	extensionKeyShare             = 0x0033
+	extensionRenegotiationInfo    = 0xff01
+)
+
+// maxServerHelloSize bounds how much is read while waiting for a ServerHello
+const maxServerHelloSize = 1 << 16
+
+var errNoServerHello = errors.New("no ServerHello received")
+
+// clientHello describes a ClientHello written byte by byte, so every field crypto/tls
+// decides on its own (cipher order, GREASE, extension order, ALPN list, ...) can be chosen
+type clientHello struct {
+	// RecordVersion is the version of the record layer, Version the legacy_version of the hello
+	RecordVersion uint16
+	Version       uint16
+	CipherSuites  []uint16
+	ServerName    string
+	// Grease adds random GREASE values to the cipher suites and extensions
+	Grease bool
+	ALPN   []string
+	// SupportedVersions is omitted from the extensions when empty
+	SupportedVersions []uint16
+}
+
+// marshal builds the TLS record carrying the ClientHello
+func (h *clientHello) marshal() []byte {
+	var greaseValue uint16
+	if h.Grease {
+		greaseValue = randomGrease()
+	}
+
+	body := binary.BigEndian.AppendUint16(nil, h.Version)
+	body = append(body, randomBytes(32)...)
+	body = append(body, 32)
+	body = append(body, randomBytes(32)...)
+
+	suites := h.CipherSuites
+	if h.Grease {
+		suites = append([]uint16{greaseValue}, suites...)
+	}
+	body = binary.BigEndian.AppendUint16(body, uint16(2*len(suites)))
+	for _, suite := range suites {
+		body = binary.BigEndian.AppendUint16(body, suite)
+	}
+	// a single compression method: null
+	body = append(body, 1, 0)
+
+	extensions := h.extensions(greaseValue)
+	body = binary.BigEndian.AppendUint16(body, uint16(len(extensions)))
+	body = append(body, extensions...)
+
+	handshake := []byte{handshakeTypeClientHello}
+	handshake = append(handshake, byte(len(body)>>16), byte(len(body)>>8), byte(len(body)))
+	handshake = append(handshake, body...)
+
+	record := []byte{recordTypeHandshake}
+	record = binary.BigEndian.AppendUint16(record, h.RecordVersion)
+	record = binary.BigEndian.AppendUint16(record, uint16(len(handshake)))
+	return append(record, handshake...)
+}
+
+func (h *clientHello) extensions(greaseValue uint16) []byte {
+	var extensions []byte
+	add := func(extensionType uint16, data []byte) {
+		extensions = binary.BigEndian.AppendUint16(extensions, extensionType)
+		extensions = binary.BigEndian.AppendUint16(extensions, uint16(len(data)))
+		extensions = append(extensions, data...)
+	}
+
+	if h.Grease {
+		add(greaseValue, nil)
+	}
+
+	serverName := []byte(h.ServerName)
+	sni := binary.BigEndian.AppendUint16(nil, uint16(len(serverName)+3))
+	sni = append(sni, 0)
+	sni = binary.BigEndian.AppendUint16(sni, uint16(len(serverName)))
+	add(extensionServerName, append(sni, serverName...))
+
+	add(extensionExtendedMasterSecret, nil)
+	add(extensionMaxFragmentLength, []byte{1})
+	add(extensionRenegotiationInfo, []byte{0})
+	// x25519, secp256r1, secp384r1, secp521r1
+	add(extensionSupportedGroups, []byte{0x00, 0x08, 0x00, 0x1d, 0x00, 0x17, 0x00, 0x18, 0x00, 0x19})
+	add(extensionECPointFormats, []byte{1, 0})
+	add(extensionSessionTicket, nil)
+
+	var protocols []byte
+	for _, protocol := range h.ALPN {
+		protocols = append(protocols, byte(len(protocol)))
+		protocols = append(protocols, protocol...)
+	}
+	add(extensionALPN, append(binary.BigEndian.AppendUint16(nil, uint16(len(protocols))), protocols...))
+
+	add(extensionSignatureAlgorithms, []byte{
+		0x00, 0x12, 0x04, 0x03, 0x08, 0x04, 0x04, 0x01, 0x05, 0x03, 0x08, 0x05, 0x05, 0x01, 0x08, 0x06, 0x06, 0x01, 0x02, 0x01,
+	})
+
+	var shares []byte
+	if h.Grease {
+		shares = binary.BigEndian.AppendUint16(shares, greaseValue)
+		shares = append(shares, 0x00, 0x01, 0x00)
+	}
+	// x25519 with a random public key, enough to get a ServerHello back
+	shares = append(shares, 0x00, 0x1d, 0x00, 0x20)
+	shares = append(shares, randomBytes(32)...)
+	add(extensionKeyShare, append(binary.BigEndian.AppendUint16(nil, uint16(len(shares))), shares...))
+
+	add(extensionPSKKeyExchangeModes, []byte{1, 1})
+
+	if len(h.SupportedVersions) > 0 {
+		var versions []byte
+		if h.Grease {
+			versions = binary.BigEndian.AppendUint16(versions, greaseValue)
+		}
+		for _, version := range h.SupportedVersions {
+			versions = binary.BigEndian.AppendUint16(versions, version)
+		}
+		add(extensionSupportedVersions, append([]byte{byte(len(versions))}, versions...))
+	}
+
+	return extensions
+}
+
+// serverHello holds the ServerHello fields used by the fingerprints
+type serverHello struct {
+	Version     uint16
+	CipherSuite uint16
+	// Extensions are the extension types in the order the server sent them
+	Extensions []uint16
+	ALPN       string
+}
+
+// sendClientHello writes hello on a new connection to addr and reads the ServerHello.
+// A handshake failure alert is returned as an error.
+func (p *Prober) sendClientHello(addr string, hello *clientHello) (*serverHello, error) {
+	conn, err := p.client.Dial(addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(p.timeout()))
+
+	if _, err := conn.Write(hello.marshal()); err != nil {
+		return nil, err
+	}
+
+	message, err := readServerHello(conn)
+	if err != nil {
+		return nil, err
+	}
+	return parseServerHello(message)
+}
+
+// readServerHello reads TLS records from conn until the first handshake message is complete
+// and returns its body, which must be a ServerHello
+func readServerHello(conn net.Conn) ([]byte, error) {
+	var handshake []byte
+	header := make([]byte, 5)
+
+	for len(handshake) < maxServerHelloSize {
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return nil, err
+		}
+		payload := make([]byte, binary.BigEndian.Uint16(header[3:5]))
+		if _, err := io.ReadFull(conn, payload); err != nil {
+			return nil, err
+		}
+
+		switch header[0] {
+		case recordTypeAlert:
+			if len(payload) == 2 {
+				return nil, fmt.Errorf("TLS alert %d", payload[1])
+			}
+			return nil, errors.New("TLS alert")
+		case recordTypeHandshake:
+			handshake = append(handshake, payload...)
+		default:
+			return nil, errNoServerHello
+		}
+
+		if len(handshake) < 4 {
+			continue
+		}
+		if handshake[0] != handshakeTypeServerHello {
+			return nil, errNoServerHello
+		}
+		length := int(handshake[1])<<16 | int(handshake[2])<<8 | int(handshake[3])
+		if len(handshake) >= 4+length {
+			return handshake[4 : 4+length], nil
+		}
+	}
+
+	return nil, errNoServerHello
+}
+
+// parseServerHello parses the body of a ServerHello handshake message
+func parseServerHello(message []byte) (*serverHello, error) {
+	malformed := errors.New("malformed ServerHello")
+
+	// legacy_version and random
+	if len(message) < 35 {
+		return nil, malformed
+	}
+	hello := &serverHello{Version: binary.BigEndian.Uint16(message)}
+	message = message[34:]
+
+	sessionIDLength := int(message[0])
+	// session id, cipher suite and compression method
+	if len(message) < 1+sessionIDLength+3 {
+		return nil, malformed
+	}
+	message = message[1+sessionIDLength:]
+	hello.CipherSuite = binary.BigEndian.Uint16(message)
+	message = message[3:]
+
+	// extensions are optional
+	if len(message) < 2 {
+		return hello, nil
+	}
+	extensions := message[2:]
+	if length := int(binary.BigEndian.Uint16(message)); length < len(extensions) {
+		extensions = extensions[:length]
+	}
+
+	for len(extensions) >= 4 {
+		extensionType := binary.BigEndian.Uint16(extensions)
+		length := int(binary.BigEndian.Uint16(extensions[2:]))
+		if len(extensions) < 4+length {
+			return nil, malformed
+		}
+		data := extensions[4 : 4+length]
+		extensions = extensions[4+length:]
+
+		hello.Extensions = append(hello.Extensions, extensionType)
+		// protocol_name_list length (2) and protocol name length (1)
+		if extensionType == extensionALPN && len(data) > 3 {
+			hello.ALPN = string(data[3:])
+		}
+	}
+
+	return hello, nil
+}
+
+// randomGrease returns one of the reserved GREASE values (0x0a0a, 0x1a1a, ..., 0xfafa)
+func randomGrease() uint16 {
+	n, _ := rand.Int(rand.Reader, big.NewInt(16))
+	value := uint16(n.Int64())<<4 | 0x0a
+	return value<<8 | value
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	rand.Read(b)
+	return b
+}
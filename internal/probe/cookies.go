@@ -0,0 +1,197 @@
+package probe
+
+import (
+	"net"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Cookie is a parsed Set-Cookie header with the issues found in its attributes
+type Cookie struct {
+	Name     string     `json:"name"`
+	Domain   string     `json:"domain,omitempty"`
+	Path     string     `json:"path,omitempty"`
+	Secure   bool       `json:"secure"`
+	HTTPOnly bool       `json:"http_only"`
+	SameSite string     `json:"same_site,omitempty"`
+	Expires  *time.Time `json:"expires,omitempty"`
+	MaxAge   int        `json:"max_age,omitempty"`
+	Size     int        `json:"size"`
+	Issues   []string   `json:"issues,omitempty"`
+}
+
+// sessionCookieFrameworks maps well known session cookie names to the technology that sets them
+var sessionCookieFrameworks = map[string]string{
+	"jsessionid":          "Java",
+	"phpsessid":           "PHP",
+	"asp.net_sessionid":   "ASP.NET",
+	"aspsessionid":        "ASP",
+	"cfid":                "ColdFusion",
+	"cftoken":             "ColdFusion",
+	"laravel_session":     "Laravel",
+	"ci_session":          "CodeIgniter",
+	"connect.sid":         "Express",
+	"_rails_session":      "Ruby on Rails",
+	"django_session":      "Django",
+	"sessionid":           "Django",
+	"csrftoken":           "Django",
+	"wordpress_logged_in": "WordPress",
+}
+
+// sessionCookieHints are substrings of cookie names that usually carry a session
+var sessionCookieHints = []string{"sess", "auth", "login", "jwt"}
+
+// sessionCookieWords must be whole words of the cookie name, so side or consider don't match
+var sessionCookieWords = []string{"sid", "token"}
+
+// csrfCookieHints mark anti-CSRF cookies, which scripts must be able to read
+var csrfCookieHints = []string{"csrf", "xsrf"}
+
+// maxCookieSize is the largest cookie browsers are required to store
+const maxCookieSize = 4096
+
+// parseCookies parses every Set-Cookie header of the response served for host
+func parseCookies(resp *fasthttp.Response, hostname string, isHTTPS bool) []Cookie {
+	var cookies []Cookie
+
+	resp.Header.VisitAllCookie(func(_, value []byte) {
+		c := fasthttp.AcquireCookie()
+		defer fasthttp.ReleaseCookie(c)
+		if err := c.ParseBytes(value); err != nil {
+			return
+		}
+
+		cookie := Cookie{
+			Name:     string(c.Key()),
+			Domain:   strings.TrimPrefix(string(c.Domain()), "."),
+			Path:     string(c.Path()),
+			Secure:   c.Secure(),
+			HTTPOnly: c.HTTPOnly(),
+			SameSite: sameSiteName(c.SameSite()),
+			MaxAge:   c.MaxAge(),
+			Size:     len(c.Key()) + len(c.Value()),
+		}
+		if expire := c.Expire(); expire != fasthttp.CookieExpireUnlimited {
+			cookie.Expires = &expire
+		}
+
+		cookie.Issues = cookieIssues(cookie, hostname, isHTTPS)
+		cookies = append(cookies, cookie)
+	})
+
+	return cookies
+}
+
+// cookieIssues flags session cookies without Secure or HttpOnly, broad domains and oversized cookies
+func cookieIssues(cookie Cookie, hostname string, isHTTPS bool) []string {
+	var issues []string
+
+	if isSessionCookie(cookie.Name) {
+		if !cookie.Secure && isHTTPS {
+			issues = append(issues, "session cookie without Secure")
+		}
+		if !cookie.HTTPOnly {
+			issues = append(issues, "session cookie without HttpOnly")
+		}
+	}
+
+	if cookie.SameSite == "None" && !cookie.Secure {
+		issues = append(issues, "SameSite=None without Secure is rejected by browsers")
+	}
+
+	if cookie.Domain != "" && net.ParseIP(hostname) == nil && !strings.EqualFold(cookie.Domain, hostname) {
+		issues = append(issues, "Domain="+cookie.Domain+" shares the cookie with every subdomain")
+	}
+
+	if cookie.Size > maxCookieSize {
+		issues = append(issues, "larger than 4096 bytes")
+	}
+
+	return issues
+}
+
+func isSessionCookie(name string) bool {
+	words := cookieNameWords(name)
+	name = strings.ToLower(name)
+	for _, hint := range csrfCookieHints {
+		if strings.Contains(name, hint) {
+			return false
+		}
+	}
+	if _, ok := sessionCookieFrameworks[name]; ok {
+		return true
+	}
+	for _, hint := range sessionCookieHints {
+		if strings.Contains(name, hint) {
+			return true
+		}
+	}
+	for _, word := range sessionCookieWords {
+		if slices.Contains(words, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// cookieNameWords splits a cookie name into lowercase words on separators and camelCase boundaries
+func cookieNameWords(name string) []string {
+	var words []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			words = append(words, word.String())
+			word.Reset()
+		}
+	}
+	for i, r := range name {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && i > 0 && unicode.IsLower(rune(name[i-1])):
+			flush()
+		}
+		word.WriteRune(unicode.ToLower(r))
+	}
+	flush()
+	return words
+}
+
+// cookieTechnologies returns the technologies identified by session cookie names
+func cookieTechnologies(cookies []Cookie) []string {
+	var technologies []string
+	seen := make(map[string]bool)
+	for _, cookie := range cookies {
+		name := strings.ToLower(cookie.Name)
+		technology, ok := sessionCookieFrameworks[name]
+		if !ok && strings.HasPrefix(name, "aspsessionid") {
+			technology, ok = sessionCookieFrameworks["aspsessionid"], true
+		}
+		if !ok && strings.HasPrefix(name, "wordpress_logged_in") {
+			technology, ok = sessionCookieFrameworks["wordpress_logged_in"], true
+		}
+		if ok && !seen[technology] {
+			seen[technology] = true
+			technologies = append(technologies, technology)
+		}
+	}
+	return technologies
+}
+
+func sameSiteName(mode fasthttp.CookieSameSite) string {
+	switch mode {
+	case fasthttp.CookieSameSiteLaxMode:
+		return "Lax"
+	case fasthttp.CookieSameSiteStrictMode:
+		return "Strict"
+	case fasthttp.CookieSameSiteNoneMode:
+		return "None"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,145 @@
+package probe
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestIsSessionCookie(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected bool
+	}{
+		{"PHPSESSID", true},
+		{"connect.sid", true},
+		{"session_id", true},
+		{"access_token", true},
+		{"refreshToken", true},
+		{"csrftoken", false},
+		{"XSRF-TOKEN", false},
+		{"_csrf", false},
+		{"side", false},
+		{"consider", false},
+		{"tokenizer_theme", false},
+		{"theme", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := isSessionCookie(tc.name); result != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestCookieIssues(t *testing.T) {
+	testCases := []struct {
+		name     string
+		cookie   Cookie
+		hostname string
+		isHTTPS  bool
+		expected []string
+	}{
+		{
+			name:     "Hardened session cookie",
+			cookie:   Cookie{Name: "sessionid", Secure: true, HTTPOnly: true, SameSite: "Lax"},
+			hostname: "example.com",
+			isHTTPS:  true,
+		},
+		{
+			name:     "Session cookie without flags",
+			cookie:   Cookie{Name: "PHPSESSID"},
+			hostname: "example.com",
+			isHTTPS:  true,
+			expected: []string{"session cookie without Secure", "session cookie without HttpOnly"},
+		},
+		{
+			name:     "Secure not expected over HTTP",
+			cookie:   Cookie{Name: "PHPSESSID", HTTPOnly: true},
+			hostname: "example.com",
+		},
+		{
+			name:     "CSRF cookie readable by scripts",
+			cookie:   Cookie{Name: "csrftoken", Secure: true},
+			hostname: "example.com",
+			isHTTPS:  true,
+		},
+		{
+			name:     "SameSite=None without Secure",
+			cookie:   Cookie{Name: "theme", SameSite: "None"},
+			hostname: "example.com",
+			expected: []string{"SameSite=None without Secure is rejected by browsers"},
+		},
+		{
+			name:     "Parent domain",
+			cookie:   Cookie{Name: "theme", Domain: "example.com"},
+			hostname: "www.example.com",
+			expected: []string{"Domain=example.com shares the cookie with every subdomain"},
+		},
+		{
+			name:     "Oversized",
+			cookie:   Cookie{Name: "theme", Size: maxCookieSize + 1},
+			hostname: "example.com",
+			expected: []string{"larger than 4096 bytes"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if issues := cookieIssues(tc.cookie, tc.hostname, tc.isHTTPS); !slices.Equal(issues, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, issues)
+			}
+		})
+	}
+}
+
+func TestParseCookies(t *testing.T) {
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+	resp.Header.Add("Set-Cookie", "PHPSESSID=abc; Path=/; HttpOnly; Secure; SameSite=Strict")
+	resp.Header.Add("Set-Cookie", "theme=dark; Domain=.example.com; Max-Age=3600; expires=Wed, 21 Oct 2037 07:28:00 GMT")
+	resp.Header.Add("Set-Cookie", "big="+strings.Repeat("a", maxCookieSize))
+
+	cookies := parseCookies(resp, "www.example.com", true)
+	if len(cookies) != 3 {
+		t.Fatalf("expected 3 cookies, got %d", len(cookies))
+	}
+
+	session := cookies[0]
+	if session.Name != "PHPSESSID" || session.Path != "/" || !session.Secure || !session.HTTPOnly || session.SameSite != "Strict" || len(session.Issues) != 0 {
+		t.Errorf("unexpected session cookie: %+v", session)
+	}
+
+	theme := cookies[1]
+	if theme.Domain != "example.com" || theme.MaxAge != 3600 || theme.Expires == nil || theme.Expires.Year() != 2037 {
+		t.Errorf("unexpected theme cookie: %+v", theme)
+	}
+	if !slices.Equal(theme.Issues, []string{"Domain=example.com shares the cookie with every subdomain"}) {
+		t.Errorf("unexpected theme issues: %v", theme.Issues)
+	}
+
+	big := cookies[2]
+	if big.Size != len("big")+maxCookieSize || !slices.Equal(big.Issues, []string{"larger than 4096 bytes"}) {
+		t.Errorf("unexpected big cookie: size %d, issues %v", big.Size, big.Issues)
+	}
+}
+
+func TestCookieTechnologies(t *testing.T) {
+	cookies := []Cookie{
+		{Name: "csrftoken"},
+		{Name: "sessionid"},
+		{Name: "ASPSESSIONIDQQGGGNCG"},
+		{Name: "wordpress_logged_in_5c0f"},
+		{Name: "JSESSIONID"},
+		{Name: "theme"},
+	}
+
+	expected := []string{"Django", "ASP", "WordPress", "Java"}
+	if technologies := cookieTechnologies(cookies); !slices.Equal(technologies, expected) {
+		t.Errorf("expected %v, got %v", expected, technologies)
+	}
+}
@@ -0,0 +1,141 @@
+package probe
+
+import (
+	"net"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// CORSFinding is a crafted Origin that the server trusted
+type CORSFinding struct {
+	Test             string `json:"test"`
+	Origin           string `json:"origin"`
+	AllowOrigin      string `json:"allow_origin"`
+	AllowCredentials bool   `json:"allow_credentials"`
+	Severity         string `json:"severity"`
+}
+
+// corsTest is a crafted Origin header derived from the target's scheme and hostname.
+// An empty origin skips the test for that target.
+type corsTest struct {
+	name   string
+	origin func(scheme, hostname string) string
+}
+
+var corsTests = []corsTest{
+	{"arbitrary origin", func(scheme, hostname string) string {
+		return "https://" + randomToken(4) + "-attacker.com"
+	}},
+	{"null origin", func(scheme, hostname string) string {
+		return "null"
+	}},
+	{"suffix match bypass", func(scheme, hostname string) string {
+		return domainOrigin(scheme, hostname, hostname+"."+randomToken(4)+"-attacker.com")
+	}},
+	{"prefix match bypass", func(scheme, hostname string) string {
+		return domainOrigin(scheme, hostname, randomToken(4)+hostname)
+	}},
+	{"unescaped dot bypass", func(scheme, hostname string) string {
+		return domainOrigin(scheme, hostname, unescapedDotHostname(hostname))
+	}},
+	{"arbitrary subdomain", func(scheme, hostname string) string {
+		return domainOrigin(scheme, hostname, randomToken(4)+"."+hostname)
+	}},
+	{"scheme downgrade", func(scheme, hostname string) string {
+		if scheme != "https" {
+			return ""
+		}
+		return "http://" + hostname
+	}},
+}
+
+// checkCORS sends every crafted Origin to the URL, with the credentials of the probe, and reports the
+// ones reflected or allowed
+func (p *Prober) checkCORS(url, pinned string, credentials *Credentials, req *fasthttp.Request, resp *fasthttp.Response) []CORSFinding {
+	uri := fasthttp.AcquireURI()
+	defer fasthttp.ReleaseURI(uri)
+	if err := uri.Parse(nil, []byte(url)); err != nil {
+		return nil
+	}
+	scheme, hostname := string(uri.Scheme()), hostnameOf(uri)
+
+	var findings []CORSFinding
+	for _, test := range corsTests {
+		origin := test.origin(scheme, hostname)
+		if origin == "" {
+			continue
+		}
+
+		req.Reset()
+		resp.Reset()
+		req.SetRequestURI(url)
+		req.Header.SetMethod(fasthttp.MethodGet)
+		for key, value := range defaultHeaders {
+			req.Header.Set(key, value)
+		}
+		req.Header.Set("Origin", origin)
+
+		if err := p.doAuthenticatedRequest(pinned, credentials, req, resp); err != nil {
+			continue
+		}
+
+		allowOrigin := string(resp.Header.Peek("Access-Control-Allow-Origin"))
+		allowCredentials := strings.EqualFold(string(resp.Header.Peek("Access-Control-Allow-Credentials")), "true")
+
+		finding := CORSFinding{
+			Test:             test.name,
+			Origin:           origin,
+			AllowOrigin:      allowOrigin,
+			AllowCredentials: allowCredentials,
+		}
+
+		switch {
+		case allowOrigin == "*" && allowCredentials:
+			finding.Test = "credentialed wildcard"
+			finding.Severity = "medium"
+		case allowOrigin == origin && allowCredentials:
+			finding.Severity = "high"
+		case allowOrigin == origin:
+			finding.Severity = "low"
+		default:
+			continue
+		}
+
+		// the trusted subdomain case is only worth reporting as informational
+		if test.name == "arbitrary subdomain" {
+			finding.Severity = "info"
+		}
+		findings = append(findings, finding)
+
+		// a credentialed wildcard is the same answer for every origin
+		if finding.Test == "credentialed wildcard" {
+			break
+		}
+	}
+
+	return findings
+}
+
+// domainOrigin returns an origin for the crafted domain, or an empty string when the target is an IP address
+func domainOrigin(scheme, hostname, domain string) string {
+	if domain == "" || net.ParseIP(hostname) != nil {
+		return ""
+	}
+	return scheme + "://" + domain
+}
+
+// unescapedDotHostname replaces the dot before the registrable domain of hostname, catching regexes like ^api.example.com$.
+// It returns an empty string for hostnames without a subdomain.
+func unescapedDotHostname(hostname string) string {
+	idx := strings.LastIndex(hostname, ".")
+	if idx <= 0 {
+		return ""
+	}
+	idx = strings.LastIndex(hostname[:idx], ".")
+	if idx <= 0 {
+		return ""
+	}
+
+	return hostname[:idx] + "x" + hostname[idx+1:]
+}
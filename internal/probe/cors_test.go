@@ -0,0 +1,75 @@
+package probe
+
+import (
+	"maps"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestCheckCORS(t *testing.T) {
+	// each path trusts origins with a different policy
+	policies := map[string]func(origin string) bool{
+		"/reflect": func(string) bool { return true },
+		"/null":    func(origin string) bool { return origin == "null" },
+		// meant to trust api.example.test and its subdomains
+		"/suffix": func(origin string) bool { return strings.HasSuffix(origin, "api.example.test") },
+		// meant to trust http://api.example.test
+		"/prefix": func(origin string) bool { return strings.HasPrefix(origin, "http://api.example.test") },
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/wildcard" {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+			return
+		}
+		if origin := r.Header.Get("Origin"); policies[r.URL.Path](origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+	}))
+	defer server.Close()
+
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	overrides := &resolveOverrides{hosts: map[string][]net.IPAddr{"api.example.test": {{IP: net.ParseIP("127.0.0.1")}}}}
+	prober := NewProber(&ProberConfig{URLs: &[]string{}, Threads: 1, Timeout: 5, Method: "GET", ResolveOverrides: overrides})
+
+	testCases := []struct {
+		path     string
+		expected map[string]string
+	}{
+		{"/reflect", map[string]string{
+			"arbitrary origin":     "high",
+			"null origin":          "high",
+			"suffix match bypass":  "high",
+			"prefix match bypass":  "high",
+			"unescaped dot bypass": "high",
+			"arbitrary subdomain":  "info",
+		}},
+		{"/null", map[string]string{"null origin": "high"}},
+		{"/suffix", map[string]string{"prefix match bypass": "high", "arbitrary subdomain": "info"}},
+		{"/prefix", map[string]string{"suffix match bypass": "high"}},
+		{"/wildcard", map[string]string{"credentialed wildcard": "medium"}},
+	}
+
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			severities := make(map[string]string)
+			for _, finding := range prober.checkCORS("http://api.example.test:"+port+tc.path, "", nil, req, resp) {
+				severities[finding.Test] = finding.Severity
+			}
+			if !maps.Equal(severities, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, severities)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package probe
+
+import (
+	"crypto/tls"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/GraveSIN/http-probe/internal/scope"
+	"github.com/GraveSIN/http-probe/internal/secheaders"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/publicsuffix"
+)
+
+// linkPattern matches the URL of href, src and action attributes
+var linkPattern = regexp.MustCompile(`(?i)(?:href|src|action)\s*=\s*["']?([^"'\s>]+)`)
+
+// maxDiscoveryBodySize bounds how much of a body is searched for links
+const maxDiscoveryBodySize = 1 << 20
+
+// discovery tracks the hostnames harvested from responses when --discover is set
+type discovery struct {
+	maxDepth int
+	// domains are the registered domains of the seed targets, which bound the discovered ones
+	// unless a scope is configured
+	domains map[string]bool
+	scope   *scope.Scope
+	// seen holds every hostname already probed or queued
+	seen sync.Map
+	// pending counts the targets queued or being probed, the work pool closes when it drops to zero
+	pending sync.WaitGroup
+
+	// queued holds the discovered targets until the feeder hands them to the work pool, so workers
+	// never block on a full pool
+	mu     sync.Mutex
+	queued []probeTarget
+	// wake signals the feeder that targets were queued
+	wake chan struct{}
+}
+
+// newDiscovery scopes discovery to targetScope, or to the registered domains of the seed URLs when it is nil
+func newDiscovery(urls []string, maxDepth int, targetScope *scope.Scope) *discovery {
+	d := &discovery{maxDepth: maxDepth, domains: make(map[string]bool), scope: targetScope, wake: make(chan struct{}, 1)}
+	for _, seed := range urls {
+		parsed, err := url.Parse(seed)
+		if err != nil {
+			continue
+		}
+		hostname := strings.ToLower(parsed.Hostname())
+		d.seen.Store(hostname, true)
+		if domain, err := publicsuffix.EffectiveTLDPlusOne(hostname); err == nil {
+			d.domains[domain] = true
+		}
+	}
+	return d
+}
+
+// inScope reports whether hostname belongs to the scope or to one of the seed domains.
+// IP addresses are never in scope.
+func (d *discovery) inScope(hostname string) bool {
+	if net.ParseIP(hostname) != nil {
+		return false
+	}
+	if d.scope != nil {
+		return d.scope.CheckHost(hostname) == nil
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(hostname)
+	return err == nil && d.domains[domain]
+}
+
+// discoverHostnames harvests the hostnames leaked by the certificate and response of target,
+// and queues the unseen in-scope ones one level deeper
+func (p *Prober) discoverHostnames(target probeTarget, uri *fasthttp.URI, resp *fasthttp.Response, result ProbeResult) {
+	if target.Depth >= p.discovery.maxDepth {
+		return
+	}
+
+	var hostnames []string
+	if string(uri.Scheme()) == "https" {
+		hostnames = append(hostnames, p.certificateNames(string(uri.Host()), target.DialAddr)...)
+	}
+	hostnames = append(hostnames, cspHostnames(string(resp.Header.Peek("Content-Security-Policy")))...)
+	if result.RedirectLocation != "" {
+		hostnames = append(hostnames, urlHostname(result.RedirectLocation))
+	}
+	hostnames = append(hostnames, linkHostnames(resp.Body())...)
+
+	for _, hostname := range hostnames {
+		hostname = strings.TrimSuffix(strings.ToLower(hostname), ".")
+		if hostname == "" || !p.discovery.inScope(hostname) {
+			continue
+		}
+		if _, seen := p.discovery.seen.LoadOrStore(hostname, true); seen {
+			continue
+		}
+		p.enqueueDiscovered("https://"+hostname, target)
+	}
+}
+
+// enqueueDiscovered queues baseURL, combined with every configured path, without blocking the calling worker
+func (p *Prober) enqueueDiscovered(baseURL string, parent probeTarget) {
+	targets := []probeTarget{{URL: baseURL}}
+	if len(p.config.Paths) > 0 {
+		targets = targets[:0]
+		for _, path := range p.config.Paths {
+			targets = append(targets, newPathTarget(baseURL, path))
+		}
+	}
+
+	for _, target := range targets {
+		target.Depth = parent.Depth + 1
+		target.DiscoveredFrom = parent.URL
+		p.discovery.push(target)
+	}
+}
+
+// push queues a discovered target for the feeder
+func (d *discovery) push(target probeTarget) {
+	d.pending.Add(1)
+	d.mu.Lock()
+	d.queued = append(d.queued, target)
+	d.mu.Unlock()
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// feed moves the queued targets to the work pool one at a time until stop is closed
+func (d *discovery) feed(workPool chan<- probeTarget, stop <-chan struct{}) {
+	for {
+		d.mu.Lock()
+		if len(d.queued) == 0 {
+			d.mu.Unlock()
+			select {
+			case <-d.wake:
+				continue
+			case <-stop:
+				return
+			}
+		}
+		target := d.queued[0]
+		d.queued[0] = probeTarget{}
+		d.queued = d.queued[1:]
+		d.mu.Unlock()
+
+		workPool <- target
+	}
+}
+
+// certificateNames returns the DNS names of the certificate served by host, cached per host and pinned address
+func (p *Prober) certificateNames(host, pinned string) []string {
+	return p.certificates.get(host+"|"+pinned, func() []string {
+		state, ok := p.tlsHandshake(host, pinned, func(config *tls.Config) {})
+		if !ok || len(state.PeerCertificates) == 0 {
+			return nil
+		}
+		certificate := state.PeerCertificates[0]
+
+		names := slices.Clone(certificate.DNSNames)
+		if certificate.Subject.CommonName != "" {
+			names = append(names, certificate.Subject.CommonName)
+		}
+		// a wildcard only tells the parent domain exists
+		for i, name := range names {
+			names[i] = strings.TrimPrefix(name, "*.")
+		}
+		return names
+	})
+}
+
+// cspHostnames extracts the hosts allowed by a Content-Security-Policy
+func cspHostnames(value string) []string {
+	var hostnames []string
+	for _, sources := range secheaders.ParseCSP(value) {
+		for _, source := range sources {
+			// keywords, nonces, hashes and bare schemes
+			if strings.HasPrefix(source, "'") || strings.HasSuffix(source, ":") {
+				continue
+			}
+			if !strings.Contains(source, "://") {
+				source = "https://" + source
+			}
+			hostnames = append(hostnames, strings.TrimPrefix(urlHostname(source), "*."))
+		}
+	}
+	return hostnames
+}
+
+// linkHostnames extracts the hosts of the absolute links in an HTML body
+func linkHostnames(body []byte) []string {
+	if len(body) > maxDiscoveryBodySize {
+		body = body[:maxDiscoveryBodySize]
+	}
+
+	var hostnames []string
+	for _, match := range linkPattern.FindAllSubmatch(body, -1) {
+		link := string(match[1])
+		if strings.HasPrefix(link, "//") {
+			link = "https:" + link
+		}
+		if strings.HasPrefix(link, "http://") || strings.HasPrefix(link, "https://") {
+			hostnames = append(hostnames, urlHostname(link))
+		}
+	}
+	return hostnames
+}
+
+func urlHostname(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return parsed.Hostname()
+}
@@ -0,0 +1,87 @@
+package probe
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestDiscoveryScope(t *testing.T) {
+	d := newDiscovery([]string{"https://www.example.com", "https://shop.example.co.uk:8443"}, 2, nil)
+
+	testCases := []struct {
+		hostname string
+		expected bool
+	}{
+		{"api.example.com", true},
+		{"example.com", true},
+		{"cdn.example.co.uk", true},
+		{"example.org", false},
+		{"co.uk", false},
+		{"10.0.0.1", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.hostname, func(t *testing.T) {
+			if result := d.inScope(tc.hostname); result != tc.expected {
+				t.Errorf("expected %t, got %t", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestResponseHostnames(t *testing.T) {
+	csp := cspHostnames("default-src 'self'; script-src 'nonce-abc' https://cdn.example.com *.static.example.com data:; img-src img.example.com:8443")
+	expected := []string{"cdn.example.com", "static.example.com", "img.example.com"}
+	for _, hostname := range expected {
+		if !slices.Contains(csp, hostname) {
+			t.Errorf("expected %s in CSP hostnames, got %v", hostname, csp)
+		}
+	}
+	if len(csp) != len(expected) {
+		t.Errorf("expected %d CSP hostnames, got %v", len(expected), csp)
+	}
+
+	body := []byte(`<a href="https://blog.example.com/post">x</a><script src=//js.example.com/app.js></script>` +
+		`<form action='/login'></form><img SRC="http://img.example.com">`)
+	links := linkHostnames(body)
+	if !slices.Equal(links, []string{"blog.example.com", "js.example.com", "img.example.com"}) {
+		t.Errorf("expected link hostnames, got %v", links)
+	}
+}
+
+func TestDiscoveryQueue(t *testing.T) {
+	d := newDiscovery(nil, 2, nil)
+
+	// pushing never waits for the work pool
+	for i := range 1000 {
+		d.push(probeTarget{URL: "https://host" + strconv.Itoa(i) + ".example.com"})
+	}
+
+	workPool := make(chan probeTarget)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.feed(workPool, stop)
+	}()
+
+	for i := range 1000 {
+		target := <-workPool
+		if expected := "https://host" + strconv.Itoa(i) + ".example.com"; target.URL != expected {
+			t.Fatalf("expected %s, got %s", expected, target.URL)
+		}
+		d.pending.Done()
+	}
+
+	// targets pushed while the feeder waits are handed over too
+	d.push(probeTarget{URL: "https://late.example.com"})
+	if target := <-workPool; target.URL != "https://late.example.com" {
+		t.Errorf("expected the late target, got %s", target.URL)
+	}
+	d.pending.Done()
+
+	d.pending.Wait()
+	close(stop)
+	<-done
+}
@@ -0,0 +1,137 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+
+	"github.com/GraveSIN/http-probe/internal/scope"
+	"github.com/valyala/fasthttp"
+)
+
+// IPv6 readiness of a target in --dual-stack mode
+const (
+	// ipv6Ready means the target answers over IPv6 like it does over IPv4
+	ipv6Ready = "ready"
+	// ipv6Mismatch means the target answers over IPv6 but not like over IPv4
+	ipv6Mismatch = "mismatch"
+	// ipv6Unreachable means the target has AAAA records but doesn't answer over IPv6
+	ipv6Unreachable = "unreachable"
+	// ipv6NoAAAA means the target has no AAAA records
+	ipv6NoAAAA = "no-aaaa"
+)
+
+// familyResolver only returns the addresses of one family, network being "ip4" or "ip6"
+type familyResolver struct {
+	resolver fasthttp.Resolver
+	network  string
+}
+
+func (r *familyResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	addrs, err := r.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var filtered []net.IPAddr
+	for _, addr := range addrs {
+		if (addr.IP.To4() != nil) == (r.network == "ip4") {
+			filtered = append(filtered, addr)
+		}
+	}
+	if len(filtered) == 0 {
+		return nil, &net.DNSError{Err: "no " + r.network + " address", Name: host, IsNotFound: true}
+	}
+	return filtered, nil
+}
+
+// isIPv6Literal reports whether the host of addr is an IPv6 address
+func isIPv6Literal(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.To4() == nil
+}
+
+// probeDualStack probes target over IPv4 and over IPv6, with the host kept for the Host header and
+// SNI, and reports on every result whether the target works over IPv6 like it does over IPv4
+func (p *Prober) probeDualStack(target probeTarget, req *fasthttp.Request, resp *fasthttp.Response) []ProbeResult {
+	parsed, err := url.Parse(target.URL)
+	if err != nil || target.DialAddr != "" || net.ParseIP(parsed.Hostname()) != nil {
+		return []ProbeResult{p.probeURL(target, req, resp)}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout())
+	addrs, err := p.lookupAddr(ctx, fasthttp.AddMissingPort(parsed.Host, parsed.Scheme == "https"))
+	cancel()
+	if err != nil {
+		var blocked *scope.BlockedError
+		if errors.As(err, &blocked) {
+			return []ProbeResult{skippedResult(target, blocked)}
+		}
+		// no AAAA record could be looked up either, which is still a verdict
+		result := failedResult(target, err)
+		result.IPv6Readiness = ipv6NoAAAA
+		return []ProbeResult{result}
+	}
+
+	// the first address of each family is the one a client of that family would use
+	var ipv4, ipv6 net.IP
+	for _, addr := range addrs {
+		if addr.IP.To4() != nil {
+			if ipv4 == nil {
+				ipv4 = addr.IP
+			}
+		} else if ipv6 == nil {
+			ipv6 = addr.IP
+		}
+	}
+
+	probe := func(ip net.IP) (ProbeResult, bool) {
+		if ip == nil {
+			return ProbeResult{}, false
+		}
+		pinned := target
+		pinned.DialAddr = ip.String()
+		result := p.probeURL(pinned, req, resp)
+		return result, result.StatusLine != "" || result.Skipped != ""
+	}
+	ipv4Result, ipv4OK := probe(ipv4)
+	ipv6Result, ipv6OK := probe(ipv6)
+
+	var results []ProbeResult
+	if ipv4OK {
+		results = append(results, ipv4Result)
+	}
+	if ipv6OK {
+		results = append(results, ipv6Result)
+	}
+	if len(results) == 0 {
+		// neither family answered, the failure keeps the target and its verdict in the output
+		if ipv4 != nil {
+			results = append(results, ipv4Result)
+		} else {
+			results = append(results, ipv6Result)
+		}
+	}
+
+	readiness := ipv6Ready
+	switch {
+	case ipv6 == nil:
+		readiness = ipv6NoAAAA
+	case ipv6Result.Skipped != "":
+		// out of scope, so not tested
+		readiness = ""
+	case !ipv6OK:
+		readiness = ipv6Unreachable
+	case ipv4OK && len(ipDisagreements(results)) > 0:
+		readiness = ipv6Mismatch
+	}
+	for i := range results {
+		results[i].IPv6Readiness = readiness
+	}
+	return results
+}
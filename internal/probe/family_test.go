@@ -0,0 +1,159 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/GraveSIN/http-probe/internal/targets"
+	"github.com/valyala/fasthttp"
+)
+
+func TestFamilyResolver(t *testing.T) {
+	addrs := staticResolver{{IP: net.ParseIP("192.0.2.1")}, {IP: net.ParseIP("2001:db8::1")}, {IP: net.ParseIP("192.0.2.2")}}
+
+	tests := []struct {
+		network  string
+		resolver staticResolver
+		expected int
+	}{
+		{"ip4", addrs, 2},
+		{"ip6", addrs, 1},
+		{"ip6", addrs[:1], 0},
+	}
+
+	for _, test := range tests {
+		resolver := &familyResolver{resolver: test.resolver, network: test.network}
+		got, err := resolver.LookupIPAddr(context.Background(), "example.com")
+		if len(got) != test.expected {
+			t.Errorf("%s: expected %d addresses, got %v", test.network, test.expected, got)
+		}
+		if test.expected == 0 && err == nil {
+			t.Errorf("%s: expected an error without addresses", test.network)
+		}
+		for _, addr := range got {
+			if (addr.IP.To4() != nil) != (test.network == "ip4") {
+				t.Errorf("%s: unexpected address %s", test.network, addr.IP)
+			}
+		}
+	}
+}
+
+func TestProbeDualStack(t *testing.T) {
+	ipv4Listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, _ := net.SplitHostPort(ipv4Listener.Addr().String())
+	ipv6Listener, err := net.Listen("tcp", "[::1]:"+port)
+	if err != nil {
+		ipv4Listener.Close()
+		t.Skipf("::1 is not available: %v", err)
+	}
+
+	for _, listener := range []net.Listener{ipv4Listener, ipv6Listener} {
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "<html><title>Dual</title></html>")
+		}))
+		server.Listener = listener
+		server.Config.ErrorLog = log.New(io.Discard, "", 0)
+		server.Start()
+		defer server.Close()
+	}
+
+	// only listens on IPv4
+	ipv4Only := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html><title>IPv4 only</title></html>")
+	}))
+	defer ipv4Only.Close()
+	_, ipv4OnlyPort, _ := net.SplitHostPort(ipv4Only.Listener.Addr().String())
+
+	// nothing listens on either family
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, closedPort, _ := net.SplitHostPort(closed.Addr().String())
+	closed.Close()
+
+	prober := NewProber(&ProberConfig{URLs: &[]string{}, Threads: 1, Timeout: 5, Method: "GET", DualStack: true})
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	tests := []struct {
+		name      string
+		addrs     fasthttp.Resolver
+		url       string
+		results   int
+		readiness string
+		failed    bool
+	}{
+		{"both families", staticResolver{{IP: net.ParseIP("127.0.0.1")}, {IP: net.ParseIP("::1")}}, "http://dual.example.test:" + port, 2, ipv6Ready, false},
+		{"no AAAA records", staticResolver{{IP: net.ParseIP("127.0.0.1")}}, "http://dual.example.test:" + port, 1, ipv6NoAAAA, false},
+		{"IPv4 only listener", staticResolver{{IP: net.ParseIP("127.0.0.1")}, {IP: net.ParseIP("::1")}}, "http://dual.example.test:" + ipv4OnlyPort, 1, ipv6Unreachable, false},
+		{"both families down", staticResolver{{IP: net.ParseIP("127.0.0.1")}, {IP: net.ParseIP("::1")}}, "http://dual.example.test:" + closedPort, 1, ipv6Unreachable, true},
+		{"lookup error", &familyResolver{resolver: staticResolver{}, network: "ip4"}, "http://dual.example.test:" + port, 1, ipv6NoAAAA, true},
+	}
+
+	for _, test := range tests {
+		prober.resolver = test.addrs
+		results := prober.probeDualStack(probeTarget{URL: test.url}, req, resp)
+		if len(results) != test.results {
+			t.Fatalf("%s: expected %d results, got %+v", test.name, test.results, results)
+		}
+		for _, result := range results {
+			if result.IPv6Readiness != test.readiness {
+				t.Errorf("%s: expected readiness %q, got %q", test.name, test.readiness, result.IPv6Readiness)
+			}
+			if (result.Error != "") != test.failed {
+				t.Errorf("%s: expected failed %v, got error %q", test.name, test.failed, result.Error)
+			}
+		}
+	}
+}
+
+func TestIPv6LiteralTargets(t *testing.T) {
+	listener, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("::1 is not available: %v", err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html><title>IPv6</title></html>")
+	}))
+	server.Listener = listener
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.Start()
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	// neither -6 nor --dual-stack is set, IPv6 literals are dialed all the same
+	prober := NewProber(&ProberConfig{URLs: &[]string{}, Threads: 1, Timeout: 5, Method: "GET"})
+	if results := prober.Probe("http://[::1]:" + port); len(results) != 1 || results[0].Title != "IPv6" {
+		t.Errorf("expected the IPv6 literal to be probed, got %+v", results)
+	}
+
+	loopback := netip.MustParseAddr("::1")
+	prober = NewProber(&ProberConfig{
+		URLs:      &[]string{},
+		Threads:   1,
+		Timeout:   5,
+		Method:    "GET",
+		Expansion: &targets.Expansion{Ranges: []targets.Range{{First: loopback, Last: loopback}}, Ports: []string{port}},
+	})
+	var results []ProbeResult
+	for result := range prober.Start() {
+		results = append(results, result)
+	}
+	// expanded addresses are tried over HTTPS first, the plain HTTP server answers the fallback
+	if len(results) != 1 || results[0].URL != "http://[::1]:"+port || results[0].Title != "IPv6" {
+		t.Errorf("expected the expanded IPv6 address to be probed, got %+v", results)
+	}
+}
@@ -0,0 +1,207 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/http2"
+)
+
+// Protocols reported in ProbeResult.Protocol
+const (
+	protocolHTTP11 = "HTTP/1.1"
+	protocolH2     = "h2"
+	protocolH2C    = "h2c"
+)
+
+// alpnProtocols are offered one at a time to list the protocols a TLS server accepts
+var alpnProtocols = []string{"h2", "http/1.1"}
+
+// http2Clients holds the transports used when --http2 is set
+type http2Clients struct {
+	// tls negotiates h2 with ALPN and falls back to HTTP/1.1
+	tls *http.Client
+	// h2c speaks HTTP/2 with prior knowledge over plaintext connections
+	h2c *http.Client
+}
+
+// newHTTP2Clients creates the HTTP/2 transports, sharing the fasthttp client's dialer and TLS settings.
+// Connections go to the pinned address when it is set, whatever the host of the requests.
+func newHTTP2Clients(client *fasthttp.Client, pinned string) *http2Clients {
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return client.Dial(pinnedAddress(pinned, addr))
+	}
+
+	tlsConfig := client.TLSConfig.Clone()
+	tlsConfig.NextProtos = []string{"h2", "http/1.1"}
+
+	noRedirect := func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return &http2Clients{
+		tls: &http.Client{
+			Transport: &http.Transport{
+				DialContext:       dial,
+				TLSClientConfig:   tlsConfig,
+				ForceAttemptHTTP2: true,
+				DisableKeepAlives: true,
+			},
+			CheckRedirect: noRedirect,
+		},
+		h2c: &http.Client{
+			Transport: &http2.Transport{
+				AllowHTTP: true,
+				DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+					return dial(ctx, network, addr)
+				},
+			},
+			CheckRedirect: noRedirect,
+		},
+	}
+}
+
+// doHTTP2Request sends req over HTTP/2, to the pinned address when set, and copies the answer into
+// resp so the rest of the prober can keep working with fasthttp types. It returns the protocol that
+// was used. Plaintext targets that don't accept the h2c upgrade are sent over HTTP/1.1.
+func (p *Prober) doHTTP2Request(req *fasthttp.Request, resp *fasthttp.Response, pinned string) (string, error) {
+	clients := p.http2
+	if pinned != "" {
+		clients = p.pinnedHTTP2.get(pinned, func() *http2Clients {
+			return newHTTP2Clients(p.client, pinned)
+		})
+	}
+
+	uri := req.URI()
+	client := clients.tls
+	if string(uri.Scheme()) == "http" {
+		if !p.supportsH2CUpgrade(string(uri.Host()), string(uri.RequestURI()), pinned) {
+			return protocolHTTP11, p.doRequest(pinned, req, resp)
+		}
+		client = clients.h2c
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout())
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, string(req.Header.Method()), uri.String(), bytes.NewReader(req.Body()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.VisitAll(func(key, value []byte) {
+		switch strings.ToLower(string(key)) {
+		case "host", "content-length", "connection":
+			// set by the transport, and connection-specific headers are not allowed in HTTP/2
+		default:
+			httpReq.Header.Add(string(key), string(value))
+		}
+	})
+	httpReq.Host = string(uri.Host())
+	httpReq.ContentLength = int64(len(req.Body()))
+
+	httpResp, err := client.Do(httpReq)
+	if err != nil {
+		return "", err
+	}
+	defer httpResp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(httpResp.Body, int64(p.client.MaxResponseBodySize)))
+	if err != nil {
+		return "", err
+	}
+
+	resp.Reset()
+	resp.SetStatusCode(httpResp.StatusCode)
+	for key, values := range httpResp.Header {
+		for _, value := range values {
+			resp.Header.Add(key, value)
+		}
+	}
+	resp.SetBody(body)
+	if httpResp.ContentLength >= 0 {
+		resp.Header.SetContentLength(int(httpResp.ContentLength))
+	}
+
+	switch {
+	case httpResp.ProtoMajor != 2:
+		return protocolHTTP11, nil
+	case client == clients.h2c:
+		return protocolH2C, nil
+	default:
+		return protocolH2, nil
+	}
+}
+
+// supportsH2CUpgrade sends an HTTP/1.1 request asking to upgrade to h2c and reports whether
+// the server switched protocols. The result is cached per host and pinned address.
+func (p *Prober) supportsH2CUpgrade(host, requestURI, pinned string) bool {
+	return p.h2cUpgrade.get(host+"|"+pinned, func() bool {
+		conn, err := p.dialTarget("http", host, pinned)
+		if err != nil {
+			return false
+		}
+		defer conn.Close()
+
+		// an empty SETTINGS payload keeps every setting at its default value
+		settings := base64.RawURLEncoding.EncodeToString(nil)
+		request := "GET " + requestURI + " HTTP/1.1\r\n" +
+			"Host: " + host + "\r\n" +
+			"User-Agent: " + userAgent + "\r\n" +
+			"Connection: Upgrade, HTTP2-Settings\r\n" +
+			"Upgrade: h2c\r\n" +
+			"HTTP2-Settings: " + settings + "\r\n\r\n"
+		if _, err := conn.Write([]byte(request)); err != nil {
+			return false
+		}
+
+		statusLine := make([]byte, len("HTTP/1.1 101"))
+		if _, err := io.ReadFull(conn, statusLine); err != nil {
+			return false
+		}
+		return strings.HasSuffix(string(statusLine), " 101")
+	})
+}
+
+// negotiatedALPN lists the protocols the TLS server accepts out of alpnProtocols, cached per host
+// and pinned address
+func (p *Prober) negotiatedALPN(host, pinned string) []string {
+	return p.alpn.get(host+"|"+pinned, func() []string {
+		var accepted []string
+		for _, protocol := range alpnProtocols {
+			if p.acceptsALPN(host, pinned, protocol) {
+				accepted = append(accepted, protocol)
+			}
+		}
+		return accepted
+	})
+}
+
+// acceptsALPN performs a TLS handshake offering only protocol and reports whether the server selected it
+func (p *Prober) acceptsALPN(host, pinned, protocol string) bool {
+	addr := fasthttp.AddMissingPort(host, true)
+	conn, err := p.client.Dial(pinnedAddress(pinned, addr))
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+
+	tlsConfig := p.client.TLSConfig.Clone()
+	tlsConfig.ServerName, _, _ = net.SplitHostPort(addr)
+	tlsConfig.NextProtos = []string{protocol}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout())
+	defer cancel()
+
+	tlsConn := tls.Client(conn, tlsConfig)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return false
+	}
+	return tlsConn.ConnectionState().NegotiatedProtocol == protocol
+}
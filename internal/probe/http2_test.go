@@ -0,0 +1,85 @@
+package probe
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+func TestDoHTTP2Request(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Proto", r.Proto)
+		w.Write([]byte(r.Method + " " + r.URL.Path))
+	})
+
+	h2 := httptest.NewUnstartedServer(handler)
+	h2.EnableHTTP2 = true
+	h2.StartTLS()
+	defer h2.Close()
+
+	http11 := httptest.NewTLSServer(handler)
+	defer http11.Close()
+
+	upgrade := httptest.NewServer(h2c.NewHandler(handler, &http2.Server{}))
+	defer upgrade.Close()
+
+	plain := httptest.NewServer(handler)
+	defer plain.Close()
+
+	testCases := []struct {
+		name        string
+		url         string
+		protocol    string
+		serverProto string
+		alpn        []string
+	}{
+		{"h2 over TLS", h2.URL, protocolH2, "HTTP/2.0", []string{"h2"}},
+		{"HTTP/1.1 only TLS server", http11.URL, protocolHTTP11, "HTTP/1.1", []string{"http/1.1"}},
+		{"h2c upgrade", upgrade.URL, protocolH2C, "HTTP/2.0", nil},
+		{"plaintext without h2c", plain.URL, protocolHTTP11, "HTTP/1.1", nil},
+	}
+
+	prober := NewProber(&ProberConfig{URLs: &[]string{}, Threads: 1, Timeout: 5, Method: "GET", HTTP2: true})
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := fasthttp.AcquireRequest()
+			resp := fasthttp.AcquireResponse()
+			defer fasthttp.ReleaseRequest(req)
+			defer fasthttp.ReleaseResponse(resp)
+			req.SetRequestURI(tc.url + "/path")
+			req.Header.SetMethod(fasthttp.MethodGet)
+
+			protocol, err := prober.doHTTP2Request(req, resp, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if protocol != tc.protocol {
+				t.Errorf("expected protocol %s, got %s", tc.protocol, protocol)
+			}
+			if proto := string(resp.Header.Peek("X-Proto")); proto != tc.serverProto {
+				t.Errorf("expected the server to see %s, got %s", tc.serverProto, proto)
+			}
+			if body := string(resp.Body()); body != "GET /path" {
+				t.Errorf("unexpected body %q", body)
+			}
+
+			results := prober.Probe(tc.url)
+			if len(results) != 1 || results[0].Protocol != tc.protocol || !slices.Equal(results[0].ALPN, tc.alpn) {
+				t.Errorf("expected protocol %s and ALPN %v, got %+v", tc.protocol, tc.alpn, results)
+			}
+		})
+	}
+
+	t.Run("ALPN listed without --http2", func(t *testing.T) {
+		prober := NewProber(&ProberConfig{URLs: &[]string{}, Threads: 1, Timeout: 5, Method: "GET"})
+		results := prober.Probe(h2.URL)
+		if len(results) != 1 || results[0].Protocol != protocolHTTP11 || !slices.Equal(results[0].ALPN, []string{"h2"}) {
+			t.Errorf("expected HTTP/1.1 to be used and h2 to be listed, got %+v", results)
+		}
+	})
+}
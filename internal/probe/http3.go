@@ -0,0 +1,180 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/quic-go/quic-go"
+)
+
+// AltService is an alternative service advertised in an Alt-Svc header
+type AltService struct {
+	Protocol string `json:"protocol"`
+	// Host is empty when the alternative is on the same host
+	Host   string `json:"host,omitempty"`
+	Port   string `json:"port"`
+	MaxAge int    `json:"max_age,omitempty"`
+}
+
+// HTTP3Result is the outcome of a QUIC connection attempt to an advertised HTTP/3 endpoint
+type HTTP3Result struct {
+	Endpoint      string        `json:"endpoint"`
+	Success       bool          `json:"success"`
+	HandshakeTime time.Duration `json:"handshake_time,omitempty"`
+	QUICVersion   string        `json:"quic_version,omitempty"`
+	ALPN          string        `json:"alpn,omitempty"`
+	Error         string        `json:"error,omitempty"`
+}
+
+// parseAltSvc parses an Alt-Svc header value such as: h3=":443"; ma=86400, h3-29="alt.example.com:443"
+func parseAltSvc(value string) []AltService {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "clear" {
+		return nil
+	}
+
+	var services []AltService
+	for _, entry := range strings.Split(value, ",") {
+		params := strings.Split(entry, ";")
+		protocol, authority, found := strings.Cut(strings.TrimSpace(params[0]), "=")
+		if !found {
+			continue
+		}
+
+		host, port, err := net.SplitHostPort(strings.Trim(authority, `"`))
+		if err != nil {
+			continue
+		}
+
+		service := AltService{Protocol: protocol, Host: host, Port: port}
+		for _, param := range params[1:] {
+			key, val, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if key == "ma" {
+				service.MaxAge, _ = strconv.Atoi(val)
+			}
+		}
+		services = append(services, service)
+	}
+
+	return services
+}
+
+// isHTTP3 reports whether the protocol id is HTTP/3 or one of its drafts (h3-29, ...)
+func isHTTP3(protocol string) bool {
+	return protocol == "h3" || strings.HasPrefix(protocol, "h3-")
+}
+
+// probeHTTP3 attempts a QUIC handshake with every HTTP/3 endpoint advertised for the host. Endpoints
+// on the host itself are reached at the pinned address when there is one, like the request was.
+func (p *Prober) probeHTTP3(hostname, pinned string, services []AltService) []HTTP3Result {
+	var results []HTTP3Result
+	seen := make(map[string]bool)
+
+	for _, service := range services {
+		if !isHTTP3(service.Protocol) {
+			continue
+		}
+
+		host := service.Host
+		if host == "" {
+			host = hostname
+		}
+		endpoint := net.JoinHostPort(host, service.Port)
+		if seen[endpoint+service.Protocol] {
+			continue
+		}
+		seen[endpoint+service.Protocol] = true
+
+		// an alternative on another host is that host's own address, whatever the request was pinned to
+		endpointPinned := ""
+		if strings.EqualFold(host, hostname) {
+			endpointPinned = pinned
+		}
+		results = append(results, p.http3.get(hostname+"|"+endpoint+"|"+service.Protocol+"|"+endpointPinned, func() HTTP3Result {
+			return p.dialQUIC(endpoint, hostname, service.Protocol, endpointPinned)
+		}))
+	}
+
+	return results
+}
+
+// dialQUIC performs a QUIC handshake with endpoint, or with the pinned address on the endpoint's port,
+// using serverName for SNI and offering the ALPN protocol
+func (p *Prober) dialQUIC(endpoint, serverName, protocol, pinned string) HTTP3Result {
+	result := HTTP3Result{Endpoint: endpoint, ALPN: protocol}
+
+	tlsConfig := p.client.TLSConfig.Clone()
+	tlsConfig.ServerName = serverName
+	tlsConfig.NextProtos = []string{protocol}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout())
+	defer cancel()
+
+	addr, err := p.quicAddr(ctx, endpoint, pinned)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	// QUIC doesn't go through the TCP dialer, so bind the UDP socket to the source address here
+	dialer, err := p.config.Sources.Dialer("udp", addr.IP, p.timeout())
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	var local *net.UDPAddr
+	if dialer.LocalAddr != nil {
+		local = dialer.LocalAddr.(*net.UDPAddr)
+	}
+	packetConn, err := net.ListenUDP("udp", local)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer packetConn.Close()
+
+	startTime := time.Now()
+	conn, err := quic.Dial(ctx, packetConn, addr, tlsConfig, &quic.Config{HandshakeIdleTimeout: p.timeout()})
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer conn.CloseWithError(0, "")
+
+	result.Success = true
+	result.HandshakeTime = time.Since(startTime)
+	result.QUICVersion = conn.ConnectionState().Version.String()
+	return result
+}
+
+// quicAddr resolves endpoint like the TCP dialer does. The pinned address replaces the host of
+// endpoint, but not its port: the alternative service may listen on another one.
+func (p *Prober) quicAddr(ctx context.Context, endpoint, pinned string) (*net.UDPAddr, error) {
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if pinned != "" {
+		if pinnedHost, _, err := net.SplitHostPort(pinned); err == nil {
+			pinned = pinnedHost
+		}
+		host, endpoint = pinned, net.JoinHostPort(pinned, port)
+	}
+	portNumber, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs, err := p.lookupAddr(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no address for %s", host)
+	}
+	return &net.UDPAddr{IP: addrs[0].IP, Port: portNumber}, nil
+}
@@ -0,0 +1,180 @@
+package probe
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/GraveSIN/http-probe/internal/source"
+	"github.com/quic-go/quic-go"
+)
+
+func TestParseAltSvc(t *testing.T) {
+	services := parseAltSvc(`h3=":443"; ma=86400, h3-29="alt.example.com:8443"; ma=3600; persist=1, h2=":443"`)
+
+	expected := []AltService{
+		{Protocol: "h3", Port: "443", MaxAge: 86400},
+		{Protocol: "h3-29", Host: "alt.example.com", Port: "8443", MaxAge: 3600},
+		{Protocol: "h2", Port: "443"},
+	}
+
+	if len(services) != len(expected) {
+		t.Fatalf("expected %d services, got %d: %+v", len(expected), len(services), services)
+	}
+	for i, service := range services {
+		if service != expected[i] {
+			t.Errorf("expected %+v, got %+v at index %d", expected[i], service, i)
+		}
+	}
+
+	if services := parseAltSvc("clear"); services != nil {
+		t.Errorf("expected no services for clear, got %+v", services)
+	}
+}
+
+func TestProbeHTTP3(t *testing.T) {
+	listener, err := quic.ListenAddr("127.0.0.1:0", testTLSConfig(t, "h3"), nil)
+	if err != nil {
+		t.Fatalf("failed to start QUIC listener: %v", err)
+	}
+	defer listener.Close()
+
+	// accepted connections are closed along with the listener
+	go func() {
+		for {
+			if _, err := listener.Accept(context.Background()); err != nil {
+				return
+			}
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	prober := NewProber(&ProberConfig{URLs: &[]string{}, Threads: 1, Timeout: 5})
+
+	results := prober.probeHTTP3("127.0.0.1", "", []AltService{
+		{Protocol: "h2", Port: port},
+		{Protocol: "h3", Port: port},
+		{Protocol: "h3-29", Port: port},
+	})
+
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d: %+v", len(results), results)
+	}
+	if !results[0].Success || results[0].QUICVersion == "" || results[0].HandshakeTime <= 0 {
+		t.Errorf("expected a successful h3 handshake, got %+v", results[0])
+	}
+	// the server only accepts h3, so the draft version must fail ALPN negotiation
+	if results[1].Success {
+		t.Errorf("expected h3-29 handshake to fail, got %+v", results[1])
+	}
+
+	// every later result for the endpoint comes from the cache
+	cached := prober.probeHTTP3("127.0.0.1", "", []AltService{{Protocol: "h3", Port: port}})
+	if len(cached) != 1 || cached[0] != results[0] {
+		t.Errorf("expected the cached h3 result %+v, got %+v", results[0], cached)
+	}
+}
+
+func TestDialQUICRouting(t *testing.T) {
+	listener, err := quic.ListenAddr("127.0.0.1:0", testTLSConfig(t, "h3"), nil)
+	if err != nil {
+		t.Fatalf("failed to start QUIC listener: %v", err)
+	}
+	defer listener.Close()
+
+	remotes := make(chan net.Addr, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept(context.Background())
+			if err != nil {
+				return
+			}
+			remotes <- conn.RemoteAddr()
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	t.Run("--resolve pins the endpoint", func(t *testing.T) {
+		overrides, err := parseResolveOverrides([]string{"quic.example.test:" + port + ":127.0.0.1"}, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		prober := NewProber(&ProberConfig{URLs: &[]string{}, Threads: 1, Timeout: 5, ResolveOverrides: overrides})
+		if result := prober.dialQUIC("quic.example.test:"+port, "quic.example.test", "h3", ""); !result.Success {
+			t.Errorf("expected the pinned handshake to succeed, got %+v", result)
+		}
+		<-remotes
+	})
+
+	t.Run("the pinned address replaces the endpoint's host", func(t *testing.T) {
+		prober := NewProber(&ProberConfig{URLs: &[]string{}, Threads: 1, Timeout: 5})
+		// a vhost address keeps its TCP port, the endpoint's port is the one used
+		for _, pinned := range []string{"127.0.0.1", "127.0.0.1:1"} {
+			if result := prober.dialQUIC("quic.example.test:"+port, "quic.example.test", "h3", pinned); !result.Success {
+				t.Errorf("%s: expected the pinned handshake to succeed, got %+v", pinned, result)
+			}
+			<-remotes
+		}
+
+		// the endpoints of the host are cached per pinned address, other hosts aren't pinned
+		results := prober.probeHTTP3("quic.example.test", "127.0.0.1", []AltService{
+			{Protocol: "h3", Port: port},
+			{Protocol: "h3", Host: "other.invalid", Port: port},
+		})
+		if len(results) != 2 || !results[0].Success || results[1].Success {
+			t.Errorf("expected only the endpoint on the pinned host to answer, got %+v", results)
+		}
+		<-remotes
+	})
+
+	t.Run("--source-ip binds the UDP socket", func(t *testing.T) {
+		sources, _ := source.New([]string{"127.0.0.2"}, "")
+		prober := NewProber(&ProberConfig{URLs: &[]string{}, Threads: 1, Timeout: 5, Sources: sources})
+		if result := prober.dialQUIC(listener.Addr().String(), "127.0.0.1", "h3", ""); !result.Success {
+			t.Skipf("127.0.0.2 is not available: %s", result.Error)
+		}
+		if remote := (<-remotes).(*net.UDPAddr); !remote.IP.Equal(net.ParseIP("127.0.0.2")) {
+			t.Errorf("expected the handshake from 127.0.0.2, got %s", remote)
+		}
+
+		ipv6Only, _ := source.New([]string{"::1"}, "")
+		prober = NewProber(&ProberConfig{URLs: &[]string{}, Threads: 1, Timeout: 5, Sources: ipv6Only})
+		if result := prober.dialQUIC(listener.Addr().String(), "127.0.0.1", "h3", ""); result.Success || result.Error == "" {
+			t.Errorf("expected no IPv4 source address to fail the handshake, got %+v", result)
+		}
+	})
+}
+
+// testTLSConfig returns a server TLS config with a self-signed certificate for 127.0.0.1
+func testTLSConfig(t *testing.T, protocols ...string) *tls.Config {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+		NextProtos:   protocols,
+	}
+}
@@ -0,0 +1,253 @@
+package probe
+
+import (
+	"cmp"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// TLSFingerprint holds the active TLS server fingerprints of a host
+type TLSFingerprint struct {
+	JARM string `json:"jarm"`
+	// JA3S is computed from the ServerHello answering the TLS 1.3 JARM probe (or the TLS 1.2 one)
+	JA3S    string `json:"ja3s,omitempty"`
+	JA3SRaw string `json:"ja3s_raw,omitempty"`
+}
+
+// jarmCipherSuites are the suites offered by the JARM probes, in their original order
+var jarmCipherSuites = []uint16{
+	0x0016, 0x0033, 0x0067, 0xc09e, 0xc0a2, 0x009e, 0x0039, 0x006b, 0xc09f, 0xc0a3, 0x009f, 0x0045, 0x00be, 0x0088,
+	0x00c4, 0x009a, 0xc008, 0xc009, 0xc023, 0xc0ac, 0xc0ae, 0xc02b, 0xc00a, 0xc024, 0xc0ad, 0xc0af, 0xc02c, 0xc072,
+	0xc073, 0xcca9, 0x1302, 0x1301, 0xcc14, 0xc007, 0xc012, 0xc013, 0xc027, 0xc02f, 0xc014, 0xc028, 0xc030, 0xc060,
+	0xc061, 0xc076, 0xc077, 0xcca8, 0x1305, 0x1304, 0x1303, 0xcc13, 0xc011, 0x000a, 0x002f, 0x003c, 0xc09c, 0xc0a0,
+	0x009c, 0x0035, 0x003d, 0xc09d, 0xc0a1, 0x009d, 0x0041, 0x00ba, 0x0084, 0x00c0, 0x0007, 0x0004, 0x0005,
+}
+
+// jarmALPN lists every protocol from weakest to strongest; the rare list leaves out h2 and http/1.1
+var (
+	jarmALPN     = []string{"http/0.9", "http/1.0", "http/1.1", "spdy/1", "spdy/2", "spdy/3", "h2", "h2c", "hq"}
+	jarmRareALPN = []string{"http/0.9", "http/1.0", "spdy/1", "spdy/2", "spdy/3", "h2c", "hq"}
+)
+
+// Orderings applied by the JARM probes to cipher suites, ALPN and supported versions
+const (
+	orderForward    = "forward"
+	orderReverse    = "reverse"
+	orderTopHalf    = "top half"
+	orderBottomHalf = "bottom half"
+	orderMiddleOut  = "middle out"
+)
+
+// jarmProbe is one of the ten ClientHellos sent to compute a JARM fingerprint
+type jarmProbe struct {
+	version uint16
+	// noTLS13Suites removes the TLS 1.3 suites from the offered ones
+	noTLS13Suites bool
+	cipherOrder   string
+	grease        bool
+	rareALPN      bool
+	// supportedVersions is the highest version in the supported_versions extension, 0 to omit it
+	supportedVersions uint16
+	extensionOrder    string
+}
+
+var jarmProbes = []jarmProbe{
+	{version: tls.VersionTLS12, cipherOrder: orderForward, supportedVersions: tls.VersionTLS12, extensionOrder: orderReverse},
+	{version: tls.VersionTLS12, cipherOrder: orderReverse, supportedVersions: tls.VersionTLS12, extensionOrder: orderForward},
+	{version: tls.VersionTLS12, cipherOrder: orderTopHalf, extensionOrder: orderForward},
+	{version: tls.VersionTLS12, cipherOrder: orderBottomHalf, rareALPN: true, extensionOrder: orderForward},
+	{version: tls.VersionTLS12, cipherOrder: orderMiddleOut, grease: true, rareALPN: true, extensionOrder: orderReverse},
+	{version: tls.VersionTLS11, cipherOrder: orderForward, extensionOrder: orderForward},
+	{version: tls.VersionTLS13, cipherOrder: orderForward, supportedVersions: tls.VersionTLS13, extensionOrder: orderReverse},
+	{version: tls.VersionTLS13, cipherOrder: orderReverse, supportedVersions: tls.VersionTLS13, extensionOrder: orderForward},
+	{version: tls.VersionTLS13, noTLS13Suites: true, cipherOrder: orderForward, supportedVersions: tls.VersionTLS13, extensionOrder: orderForward},
+	{version: tls.VersionTLS13, cipherOrder: orderMiddleOut, grease: true, supportedVersions: tls.VersionTLS13, extensionOrder: orderReverse},
+}
+
+// clientHello builds the probe's ClientHello for serverName
+func (probe jarmProbe) clientHello(serverName string) *clientHello {
+	hello := &clientHello{
+		RecordVersion: probe.version,
+		Version:       probe.version,
+		ServerName:    serverName,
+		Grease:        probe.grease,
+	}
+	// TLS 1.3 is negotiated through supported_versions, on top of a TLS 1.2 hello in a TLS 1.0 record
+	if probe.version == tls.VersionTLS13 {
+		hello.RecordVersion, hello.Version = tls.VersionTLS10, tls.VersionTLS12
+	}
+
+	suites := jarmCipherSuites
+	if probe.noTLS13Suites {
+		suites = slices.DeleteFunc(slices.Clone(suites), func(suite uint16) bool { return suite>>8 == 0x13 })
+	}
+	hello.CipherSuites = reorder(suites, probe.cipherOrder)
+
+	hello.ALPN = jarmALPN
+	if probe.rareALPN {
+		hello.ALPN = jarmRareALPN
+	}
+	hello.ALPN = reorder(hello.ALPN, probe.extensionOrder)
+
+	for version := uint16(tls.VersionTLS10); version <= probe.supportedVersions; version++ {
+		hello.SupportedVersions = append(hello.SupportedVersions, version)
+	}
+	hello.SupportedVersions = reorder(hello.SupportedVersions, probe.extensionOrder)
+
+	return hello
+}
+
+// reorder applies one of the JARM orderings to values
+func reorder[T any](values []T, order string) []T {
+	half := len(values) / 2
+
+	switch order {
+	case orderReverse:
+		reversed := slices.Clone(values)
+		slices.Reverse(reversed)
+		return reversed
+	case orderBottomHalf:
+		return values[len(values)-half:]
+	case orderTopHalf:
+		// the top half keeps the middle value, reversed like the rest
+		var top []T
+		if len(values)%2 == 1 {
+			top = append(top, values[half])
+		}
+		return append(top, reorder(reorder(values, orderReverse), orderBottomHalf)...)
+	case orderMiddleOut:
+		var middleOut []T
+		if len(values)%2 == 1 {
+			middleOut = append(middleOut, values[half])
+			for i := 1; i <= half; i++ {
+				middleOut = append(middleOut, values[half+i], values[half-i])
+			}
+		} else {
+			for i := 1; i <= half; i++ {
+				middleOut = append(middleOut, values[half-1+i], values[half-i])
+			}
+		}
+		return middleOut
+	default:
+		return values
+	}
+}
+
+// fingerprintTLS computes the JARM and JA3S fingerprints of the host, cached per host and pinned address
+func (p *Prober) fingerprintTLS(host, pinned string) *TLSFingerprint {
+	return p.tlsFingerprints.get(host+"|"+pinned, func() *TLSFingerprint {
+		addr := fasthttp.AddMissingPort(host, true)
+		serverName, _, _ := net.SplitHostPort(addr)
+
+		answers := make([]string, len(jarmProbes))
+		hellos := make([]*serverHello, len(jarmProbes))
+		for i, probe := range jarmProbes {
+			hello, err := p.sendClientHello(pinnedAddress(pinned, addr), probe.clientHello(serverName))
+			if err != nil {
+				answers[i] = "|||"
+				continue
+			}
+			answers[i] = jarmAnswer(hello)
+			hellos[i] = hello
+		}
+
+		fingerprint := &TLSFingerprint{JARM: jarmHash(answers)}
+		// probes 6 and 0 are the forward TLS 1.3 and TLS 1.2 ones
+		ja3sHello := cmp.Or(hellos[6], hellos[0])
+		if ja3sHello != nil {
+			fingerprint.JA3SRaw = ja3sString(ja3sHello)
+			sum := md5.Sum([]byte(fingerprint.JA3SRaw))
+			fingerprint.JA3S = hex.EncodeToString(sum[:])
+		}
+		return fingerprint
+	})
+}
+
+// jarmAnswer formats a ServerHello as cipher|version|alpn|extensions
+func jarmAnswer(hello *serverHello) string {
+	extensions := make([]string, len(hello.Extensions))
+	for i, extension := range hello.Extensions {
+		extensions[i] = fmt.Sprintf("%04x", extension)
+	}
+	return fmt.Sprintf("%04x|%04x|%s|%s", hello.CipherSuite, hello.Version, hello.ALPN, strings.Join(extensions, "-"))
+}
+
+// jarmHash turns the ten probe answers into the 62 character JARM fingerprint: a code for the cipher
+// and version picked by each probe, then a truncated SHA-256 of every ALPN and extension list
+func jarmHash(answers []string) string {
+	if !slices.ContainsFunc(answers, func(answer string) bool { return answer != "|||" }) {
+		return strings.Repeat("0", 62)
+	}
+
+	var fuzzy, alpnAndExtensions strings.Builder
+	for _, answer := range answers {
+		components := strings.Split(answer, "|")
+		fuzzy.WriteString(jarmCipherCode(components[0]))
+		fuzzy.WriteString(jarmVersionCode(components[1]))
+		alpnAndExtensions.WriteString(components[2] + components[3])
+	}
+
+	sum := sha256.Sum256([]byte(alpnAndExtensions.String()))
+	return fuzzy.String() + hex.EncodeToString(sum[:])[:32]
+}
+
+// jarmCipherCode is the position of the cipher in the sorted JARM suite list, as two hex digits
+func jarmCipherCode(cipher string) string {
+	if cipher == "" {
+		return "00"
+	}
+
+	position := len(jarmSortedCipherSuites) + 1
+	for i, suite := range jarmSortedCipherSuites {
+		if fmt.Sprintf("%04x", suite) == cipher {
+			position = i + 1
+			break
+		}
+	}
+	return fmt.Sprintf("%02x", position)
+}
+
+// jarmSortedCipherSuites is jarmCipherSuites sorted with the TLS 1.3 suites last, like in the reference implementation
+var jarmSortedCipherSuites = func() []uint16 {
+	sortKey := func(suite uint16) uint32 {
+		if suite>>8 == 0x13 {
+			return 1<<16 | uint32(suite)
+		}
+		return uint32(suite)
+	}
+	sorted := slices.Clone(jarmCipherSuites)
+	slices.SortFunc(sorted, func(a, b uint16) int {
+		return cmp.Compare(sortKey(a), sortKey(b))
+	})
+	return sorted
+}()
+
+// jarmVersionCode maps 0300 to 0304 onto a to e
+func jarmVersionCode(version string) string {
+	if len(version) != 4 {
+		return "0"
+	}
+	minor, err := strconv.Atoi(version[3:])
+	if err != nil || minor > 5 {
+		return "0"
+	}
+	return string("abcdef"[minor])
+}
+
+// ja3sString formats a ServerHello as SSLVersion,Cipher,Extensions with decimal values
+func ja3sString(hello *serverHello) string {
+	extensions := make([]string, len(hello.Extensions))
+	for i, extension := range hello.Extensions {
+		extensions[i] = strconv.Itoa(int(extension))
+	}
+	return fmt.Sprintf("%d,%d,%s", hello.Version, hello.CipherSuite, strings.Join(extensions, "-"))
+}
@@ -0,0 +1,86 @@
+package probe
+
+import (
+	"crypto/tls"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReorder(t *testing.T) {
+	testCases := []struct {
+		order    string
+		values   []int
+		expected []int
+	}{
+		{orderForward, []int{1, 2, 3, 4, 5}, []int{1, 2, 3, 4, 5}},
+		{orderReverse, []int{1, 2, 3, 4, 5}, []int{5, 4, 3, 2, 1}},
+		{orderBottomHalf, []int{1, 2, 3, 4, 5}, []int{4, 5}},
+		{orderBottomHalf, []int{1, 2, 3, 4}, []int{3, 4}},
+		{orderTopHalf, []int{1, 2, 3, 4, 5}, []int{3, 2, 1}},
+		{orderTopHalf, []int{1, 2, 3, 4}, []int{2, 1}},
+		{orderMiddleOut, []int{1, 2, 3, 4, 5}, []int{3, 4, 2, 5, 1}},
+		{orderMiddleOut, []int{1, 2, 3, 4}, []int{3, 2, 4, 1}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.order, func(t *testing.T) {
+			if result := reorder(tc.values, tc.order); !slices.Equal(result, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestJARMHash(t *testing.T) {
+	failed := slices.Repeat([]string{"|||"}, 10)
+	if hash := jarmHash(failed); hash != strings.Repeat("0", 62) {
+		t.Errorf("expected an all zero fingerprint, got %s", hash)
+	}
+
+	answers := slices.Clone(failed)
+	answers[0] = "c02f|0303|h2|ff01-0000-0001-000b-0023-0010"
+	hash := jarmHash(answers)
+	if len(hash) != 62 {
+		t.Fatalf("expected 62 characters, got %d: %s", len(hash), hash)
+	}
+	// c02f is the 41st suite in the sorted list and 0303 maps to d
+	if !strings.HasPrefix(hash, "29d000") {
+		t.Errorf("expected prefix 29d000, got %s", hash)
+	}
+}
+
+func TestFingerprintTLS(t *testing.T) {
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", testTLSConfig(t, "h2", "http/1.1"))
+	if err != nil {
+		t.Fatalf("failed to start TLS listener: %v", err)
+	}
+	defer listener.Close()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.(*tls.Conn).Handshake()
+			}()
+		}
+	}()
+
+	prober := NewProber(&ProberConfig{URLs: &[]string{}, Threads: 1, Timeout: 5})
+	fingerprint := prober.fingerprintTLS(listener.Addr().String(), "")
+
+	if len(fingerprint.JARM) != 62 || fingerprint.JARM == strings.Repeat("0", 62) {
+		t.Errorf("expected a JARM fingerprint, got %s", fingerprint.JARM)
+	}
+	// TLS 1.3 ServerHellos carry supported_versions (43) and key_share (51)
+	if !strings.HasPrefix(fingerprint.JA3SRaw, "771,") || !strings.HasSuffix(fingerprint.JA3SRaw, ",43-51") {
+		t.Errorf("expected a TLS 1.3 JA3S string, got %s", fingerprint.JA3SRaw)
+	}
+	if len(fingerprint.JA3S) != 32 {
+		t.Errorf("expected an MD5 JA3S hash, got %s", fingerprint.JA3S)
+	}
+}
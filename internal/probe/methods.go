@@ -0,0 +1,185 @@
+package probe
+
+import (
+	"bytes"
+	"path"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// methodsToScan lists the methods tried on every URL in method discovery mode, after OPTIONS
+var methodsToScan = []string{
+	fasthttp.MethodGet,
+	fasthttp.MethodHead,
+	fasthttp.MethodPost,
+	fasthttp.MethodPut,
+	fasthttp.MethodDelete,
+	fasthttp.MethodPatch,
+	fasthttp.MethodTrace,
+	fasthttp.MethodConnect,
+}
+
+// destructiveMethods are sent to a random sibling path so the probed resource is never modified.
+// PUT creates that path and DELETE removes it again, see checkUpload.
+var destructiveMethods = map[string]bool{
+	fasthttp.MethodPut:    true,
+	fasthttp.MethodDelete: true,
+	fasthttp.MethodPatch:  true,
+}
+
+const traceHeader = "X-Probe-Trace"
+
+// MethodScanResult holds the outcome of HTTP method discovery on a URL
+type MethodScanResult struct {
+	// Allow is the Allow header returned for OPTIONS
+	Allow string `json:"allow,omitempty"`
+	// Statuses maps each tried method to its response status code
+	Statuses map[string]int `json:"statuses"`
+	// Accepted lists methods that did not answer with 405 or 501
+	Accepted []string `json:"accepted,omitempty"`
+	// Dangerous describes risky behaviour such as TRACE echo or unauthenticated PUT
+	Dangerous []string `json:"dangerous,omitempty"`
+}
+
+// scanMethods sends OPTIONS and then every scan method and configured custom verb to the URL, with the
+// credentials of the probe
+func (p *Prober) scanMethods(url, pinned string, credentials *Credentials, req *fasthttp.Request, resp *fasthttp.Response) *MethodScanResult {
+	result := &MethodScanResult{
+		Statuses: make(map[string]int),
+	}
+
+	methods := append([]string{fasthttp.MethodOptions}, methodsToScan...)
+	methods = append(methods, p.config.ExtraMethods...)
+
+	// PUT and DELETE share the same random path, so DELETE removes what PUT created
+	uploadURL := siblingURL(url, randomToken(8)+".txt")
+	uploadToken := randomToken(8)
+	uploaded := false
+
+	for _, method := range methods {
+		method = strings.ToUpper(method)
+		if _, done := result.Statuses[method]; done {
+			continue
+		}
+
+		targetURL := url
+		switch {
+		case method == fasthttp.MethodPut || method == fasthttp.MethodDelete:
+			targetURL = uploadURL
+		case destructiveMethods[method]:
+			targetURL = siblingURL(url, randomToken(8)+".txt")
+		}
+
+		traceToken := randomToken(8)
+		var body string
+		req.Reset()
+		switch method {
+		case fasthttp.MethodTrace:
+			req.Header.Set(traceHeader, traceToken)
+		case fasthttp.MethodPut:
+			body = uploadToken
+		}
+
+		status, ok := p.sendMethod(method, targetURL, body, pinned, credentials, req, resp)
+		if !ok {
+			continue
+		}
+		result.Statuses[method] = status
+
+		if method == fasthttp.MethodOptions {
+			result.Allow = string(resp.Header.Peek("Allow"))
+			continue
+		}
+
+		if status != fasthttp.StatusMethodNotAllowed && status != fasthttp.StatusNotImplemented {
+			result.Accepted = append(result.Accepted, method)
+		}
+
+		switch {
+		case method == fasthttp.MethodTrace && bytes.Contains(resp.Body(), []byte(traceToken)):
+			result.Dangerous = append(result.Dangerous, "TRACE echoes request headers")
+		case method == fasthttp.MethodPut && successStatus(status):
+			uploaded = p.serves(uploadURL, uploadToken, pinned, credentials, req, resp)
+		}
+	}
+
+	if uploaded {
+		result.Dangerous = append(result.Dangerous, p.removeUpload(result, uploadURL, uploadToken, pinned, credentials, req, resp)...)
+	}
+	return result
+}
+
+// removeUpload makes sure the file the scan uploaded with PUT is removed. PUT and DELETE are only
+// reported when the upload could be read back and, for DELETE, was gone afterwards, so servers
+// answering 200 to every method aren't flagged.
+func (p *Prober) removeUpload(result *MethodScanResult, uploadURL, uploadToken, pinned string, credentials *Credentials, req *fasthttp.Request, resp *fasthttp.Response) []string {
+	authentication := "unauthenticated"
+	if credentials != nil {
+		authentication = "authenticated"
+	}
+	dangerous := []string{authentication + " PUT accepted"}
+
+	// DELETE is sent after PUT by the scan, send it again if that request failed
+	if _, tried := result.Statuses[fasthttp.MethodDelete]; !tried {
+		req.Reset()
+		p.sendMethod(fasthttp.MethodDelete, uploadURL, "", pinned, credentials, req, resp)
+	}
+	switch {
+	case p.serves(uploadURL, uploadToken, pinned, credentials, req, resp):
+		dangerous = append(dangerous, "uploaded file could not be removed: "+uploadURL)
+	case successStatus(result.Statuses[fasthttp.MethodDelete]):
+		dangerous = append(dangerous, authentication+" DELETE accepted")
+	}
+	return dangerous
+}
+
+// serves reports whether a GET of url answers with a body containing token
+func (p *Prober) serves(url, token, pinned string, credentials *Credentials, req *fasthttp.Request, resp *fasthttp.Response) bool {
+	req.Reset()
+	status, ok := p.sendMethod(fasthttp.MethodGet, url, "", pinned, credentials, req, resp)
+	return ok && status == fasthttp.StatusOK && bytes.Contains(resp.Body(), []byte(token))
+}
+
+// sendMethod sends a request with method to url, with a plain text body when body is not empty, and
+// returns the response status. Headers already set on req are sent too.
+func (p *Prober) sendMethod(method, url, body, pinned string, credentials *Credentials, req *fasthttp.Request, resp *fasthttp.Response) (int, bool) {
+	resp.Reset()
+	req.SetRequestURI(url)
+	req.Header.SetMethod(method)
+	for key, value := range defaultHeaders {
+		req.Header.Set(key, value)
+	}
+	req.SetBodyString(body)
+	if body != "" {
+		req.Header.Set("Content-Type", "text/plain")
+	}
+
+	if err := p.doAuthenticatedRequest(pinned, credentials, req, resp); err != nil {
+		return 0, false
+	}
+	return resp.StatusCode(), true
+}
+
+func successStatus(status int) bool {
+	return status >= 200 && status < 300
+}
+
+// siblingURL replaces the last path segment of a URL with name, dropping any query
+func siblingURL(url, name string) string {
+	uri := fasthttp.AcquireURI()
+	defer fasthttp.ReleaseURI(uri)
+
+	if err := uri.Parse(nil, []byte(url)); err != nil {
+		return url
+	}
+
+	dir := path.Dir(string(uri.Path()))
+	if strings.HasSuffix(string(uri.Path()), "/") {
+		dir = strings.TrimSuffix(string(uri.Path()), "/")
+	}
+	uri.SetPath(strings.TrimSuffix(dir, "/") + "/" + name)
+	uri.SetQueryString("")
+
+	return uri.String()
+}
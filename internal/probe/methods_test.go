@@ -0,0 +1,84 @@
+package probe
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+// fileServer stores PUT bodies in memory and serves them back until they are deleted
+type fileServer struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func (s *fileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		s.files[r.URL.Path], _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodDelete:
+		if _, ok := s.files[r.URL.Path]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		delete(s.files, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodGet:
+		if body, ok := s.files[r.URL.Path]; ok {
+			w.Write(body)
+			return
+		}
+		w.Write([]byte("index"))
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestScanMethods(t *testing.T) {
+	files := &fileServer{files: make(map[string][]byte)}
+	writable := httptest.NewServer(files)
+	defer writable.Close()
+
+	// answers 200 to every method without storing anything
+	catchAll := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer catchAll.Close()
+
+	tests := []struct {
+		name      string
+		url       string
+		dangerous []string
+	}{
+		{"writable server", writable.URL + "/app/index.html", []string{"unauthenticated PUT accepted", "unauthenticated DELETE accepted"}},
+		{"catch-all server", catchAll.URL + "/", nil},
+	}
+
+	prober := NewProber(&ProberConfig{URLs: &[]string{}, Threads: 1, Timeout: 5, Method: "GET"})
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := prober.scanMethods(tt.url, "", nil, req, resp)
+			if !slices.Equal(result.Dangerous, tt.dangerous) {
+				t.Errorf("expected dangerous %v, got %v", tt.dangerous, result.Dangerous)
+			}
+		})
+	}
+
+	if len(files.files) != 0 {
+		t.Errorf("expected the uploaded file to be removed, found %v", files.files)
+	}
+}
@@ -0,0 +1,189 @@
+package probe
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/valyala/fasthttp"
+	"golang.org/x/crypto/md4"
+)
+
+const (
+	ntlmSignature = "NTLMSSP\x00"
+
+	ntlmNegotiateUnicode                 = 0x00000001
+	ntlmNegotiateOEM                     = 0x00000002
+	ntlmRequestTarget                    = 0x00000004
+	ntlmNegotiateNTLM                    = 0x00000200
+	ntlmNegotiateAlwaysSign              = 0x00008000
+	ntlmNegotiateExtendedSecurity        = 0x00080000
+	ntlmNegotiateTargetInfo              = 0x00800000
+	ntlmNegotiate128                     = 0x20000000
+	ntlmNegotiate56                      = 0x80000000
+	ntlmNegotiateFlags            uint32 = ntlmNegotiateUnicode | ntlmNegotiateOEM | ntlmRequestTarget | ntlmNegotiateNTLM |
+		ntlmNegotiateAlwaysSign | ntlmNegotiateExtendedSecurity | ntlmNegotiateTargetInfo | ntlmNegotiate128 | ntlmNegotiate56
+)
+
+var errNoNTLMChallenge = errors.New("server did not send an NTLM challenge")
+
+// doNTLMRequest runs the NTLM negotiate/challenge/authenticate exchange on a single connection,
+// since NTLM authenticates the connection rather than the request. req must already be fully set up.
+func (p *Prober) doNTLMRequest(req *fasthttp.Request, resp *fasthttp.Response, credentials *Credentials, pinned string) error {
+	uri := req.URI()
+	conn, err := p.dialTarget(string(uri.Scheme()), string(uri.Host()), pinned)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	roundTrip := func() error {
+		resp.Reset()
+		writer := bufio.NewWriter(conn)
+		if err := req.Write(writer); err != nil {
+			return err
+		}
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		return resp.ReadLimitBody(reader, p.client.MaxResponseBodySize)
+	}
+
+	req.Header.Set("Connection", "keep-alive")
+	req.Header.Set("Authorization", "NTLM "+base64.StdEncoding.EncodeToString(ntlmNegotiateMessage()))
+	if err := roundTrip(); err != nil {
+		return err
+	}
+
+	challenge, err := base64.StdEncoding.DecodeString(findChallenge(resp, "NTLM"))
+	if err != nil || len(challenge) == 0 {
+		// not an NTLM protected resource, keep the response as is
+		return nil
+	}
+
+	authenticate, err := ntlmAuthenticateMessage(challenge, credentials)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Connection", connection)
+	req.Header.Set("Authorization", "NTLM "+base64.StdEncoding.EncodeToString(authenticate))
+	return roundTrip()
+}
+
+// ntlmNegotiateMessage builds the type 1 message without domain or workstation
+func ntlmNegotiateMessage() []byte {
+	msg := make([]byte, 32)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], 1)
+	binary.LittleEndian.PutUint32(msg[12:], ntlmNegotiateFlags)
+	// empty domain and workstation security buffers point at the end of the message
+	binary.LittleEndian.PutUint32(msg[20:], 32)
+	binary.LittleEndian.PutUint32(msg[28:], 32)
+	return msg
+}
+
+// ntlmAuthenticateMessage answers a type 2 challenge with an NTLMv2 type 3 message
+func ntlmAuthenticateMessage(challenge []byte, credentials *Credentials) ([]byte, error) {
+	clientChallenge := make([]byte, 8)
+	rand.Read(clientChallenge)
+	return buildNTLMAuthenticateMessage(challenge, credentials, clientChallenge, windowsFileTime(time.Now()))
+}
+
+// buildNTLMAuthenticateMessage builds the type 3 message for a client challenge and a timestamp in
+// Windows file time
+func buildNTLMAuthenticateMessage(challenge []byte, credentials *Credentials, clientChallenge []byte, timestamp uint64) ([]byte, error) {
+	if len(challenge) < 48 || string(challenge[:8]) != ntlmSignature || binary.LittleEndian.Uint32(challenge[8:]) != 2 {
+		return nil, errNoNTLMChallenge
+	}
+
+	flags := binary.LittleEndian.Uint32(challenge[20:])
+	serverChallenge := challenge[24:32]
+	targetInfoLen := int(binary.LittleEndian.Uint16(challenge[40:]))
+	targetInfoOffset := int(binary.LittleEndian.Uint32(challenge[44:]))
+	if targetInfoOffset+targetInfoLen > len(challenge) {
+		return nil, errNoNTLMChallenge
+	}
+	targetInfo := challenge[targetInfoOffset : targetInfoOffset+targetInfoLen]
+
+	ntowf := ntowfv2(credentials)
+	var blob bytes.Buffer
+	blob.Write([]byte{1, 1, 0, 0, 0, 0, 0, 0})
+	binary.Write(&blob, binary.LittleEndian, timestamp)
+	blob.Write(clientChallenge)
+	blob.Write([]byte{0, 0, 0, 0})
+	blob.Write(targetInfo)
+	blob.Write([]byte{0, 0, 0, 0})
+
+	ntProof := hmacMD5(ntowf, serverChallenge, blob.Bytes())
+	ntResponse := append(ntProof, blob.Bytes()...)
+	lmResponse := append(hmacMD5(ntowf, serverChallenge, clientChallenge), clientChallenge...)
+
+	domain := utf16LE(credentials.Domain)
+	user := utf16LE(credentials.Username)
+	workstation := utf16LE("")
+
+	const headerLen = 64
+	msg := make([]byte, headerLen)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], 3)
+
+	payload := []struct {
+		offset int
+		data   []byte
+	}{
+		{12, lmResponse},
+		{20, ntResponse},
+		{28, domain},
+		{36, user},
+		{44, workstation},
+		{52, nil},
+	}
+	for _, field := range payload {
+		binary.LittleEndian.PutUint16(msg[field.offset:], uint16(len(field.data)))
+		binary.LittleEndian.PutUint16(msg[field.offset+2:], uint16(len(field.data)))
+		binary.LittleEndian.PutUint32(msg[field.offset+4:], uint32(len(msg)))
+		msg = append(msg, field.data...)
+	}
+	binary.LittleEndian.PutUint32(msg[60:], flags&ntlmNegotiateFlags)
+
+	return msg, nil
+}
+
+// ntowfv2 = HMAC_MD5(MD4(UNICODE(password)), UNICODE(UPPER(user) + domain))
+func ntowfv2(credentials *Credentials) []byte {
+	hash := md4.New()
+	hash.Write(utf16LE(credentials.Password))
+	return hmacMD5(hash.Sum(nil), utf16LE(strings.ToUpper(credentials.Username)+credentials.Domain))
+}
+
+func hmacMD5(key []byte, data ...[]byte) []byte {
+	mac := hmac.New(md5.New, key)
+	for _, d := range data {
+		mac.Write(d)
+	}
+	return mac.Sum(nil)
+}
+
+func utf16LE(s string) []byte {
+	encoded := utf16.Encode([]rune(s))
+	buf := make([]byte, 2*len(encoded))
+	for i, r := range encoded {
+		binary.LittleEndian.PutUint16(buf[2*i:], r)
+	}
+	return buf
+}
+
+// windowsFileTime converts t to 100ns intervals since January 1, 1601
+func windowsFileTime(t time.Time) uint64 {
+	return uint64(t.UnixNano()/100) + 116444736000000000
+}
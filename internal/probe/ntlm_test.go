@@ -0,0 +1,66 @@
+package probe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+)
+
+func TestNTLMNegotiateMessage(t *testing.T) {
+	msg := ntlmNegotiateMessage()
+	if len(msg) != 32 || string(msg[:8]) != ntlmSignature || binary.LittleEndian.Uint32(msg[8:]) != 1 {
+		t.Fatalf("invalid negotiate message %x", msg)
+	}
+	if flags := binary.LittleEndian.Uint32(msg[12:]); flags != ntlmNegotiateFlags {
+		t.Errorf("expected flags %08x, got %08x", ntlmNegotiateFlags, flags)
+	}
+}
+
+// TestNTLMAuthenticateMessage checks the type 3 message against the NTLMv2 test vectors of MS-NLMP section 4.2.4
+func TestNTLMAuthenticateMessage(t *testing.T) {
+	credentials := &Credentials{Scheme: authNTLM, Domain: "Domain", Username: "User", Password: "Password"}
+	if ntowf := hex.EncodeToString(ntowfv2(credentials)); ntowf != "0c868a403bfd7a93a3001ef22ef02e3f" {
+		t.Errorf("unexpected NTOWFv2 %s", ntowf)
+	}
+
+	// MsvAvNbDomainName "Domain", MsvAvNbComputerName "Server", MsvAvEOL
+	targetInfo := append([]byte{2, 0, 12, 0}, utf16LE("Domain")...)
+	targetInfo = append(append(targetInfo, 1, 0, 12, 0), utf16LE("Server")...)
+	targetInfo = append(targetInfo, 0, 0, 0, 0)
+
+	challenge := make([]byte, 48)
+	copy(challenge, ntlmSignature)
+	binary.LittleEndian.PutUint32(challenge[8:], 2)
+	binary.LittleEndian.PutUint32(challenge[20:], 0xe28a8233)
+	copy(challenge[24:], []byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef})
+	binary.LittleEndian.PutUint16(challenge[40:], uint16(len(targetInfo)))
+	binary.LittleEndian.PutUint16(challenge[42:], uint16(len(targetInfo)))
+	binary.LittleEndian.PutUint32(challenge[44:], 48)
+	challenge = append(challenge, targetInfo...)
+
+	clientChallenge := bytes.Repeat([]byte{0xaa}, 8)
+	msg, err := buildNTLMAuthenticateMessage(challenge, credentials, clientChallenge, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	field := func(offset int) []byte {
+		length := binary.LittleEndian.Uint16(msg[offset:])
+		start := binary.LittleEndian.Uint32(msg[offset+4:])
+		return msg[start : start+uint32(length)]
+	}
+	if lm := hex.EncodeToString(field(12)); lm != "86c35097ac9cec102554764a57cccc19aaaaaaaaaaaaaaaa" {
+		t.Errorf("unexpected LMv2 response %s", lm)
+	}
+	if ntProof := hex.EncodeToString(field(20)[:16]); ntProof != "68cd0ab851e51c96aabc927bebef6a1c" {
+		t.Errorf("unexpected NTProofStr %s", ntProof)
+	}
+	if !bytes.Equal(field(28), utf16LE("Domain")) || !bytes.Equal(field(36), utf16LE("User")) {
+		t.Errorf("unexpected domain %x or user %x", field(28), field(36))
+	}
+
+	if _, err := buildNTLMAuthenticateMessage(challenge[:40], credentials, clientChallenge, 0); err == nil {
+		t.Error("expected an error for a truncated challenge")
+	}
+}
@@ -0,0 +1,90 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/url"
+	"time"
+)
+
+// ConnectionTimings are the durations of the phases before a request is sent, measured on a
+// connection of their own since fasthttp doesn't expose them
+type ConnectionTimings struct {
+	Resolve time.Duration
+	Connect time.Duration
+	// TLS and the fields below are only set for HTTPS targets that complete a handshake
+	TLS        time.Duration
+	TLSVersion string
+	// CertificateExpiry is the earliest expiry of the certificates the server sent
+	CertificateExpiry time.Time
+	// IP is the address the connection was made to
+	IP net.IP
+}
+
+// Timings resolves the host of rawURL, connects to its first address and completes a TLS handshake
+// for HTTPS, timing each phase. It stops at the first phase that fails.
+func (p *Prober) Timings(rawURL string) (ConnectionTimings, error) {
+	var timings ConnectionTimings
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return timings, err
+	}
+	port := parsed.Port()
+	if port == "" {
+		port = "80"
+		if parsed.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout())
+	defer cancel()
+
+	start := time.Now()
+	addrs, err := p.lookupAddr(ctx, net.JoinHostPort(parsed.Hostname(), port))
+	timings.Resolve = time.Since(start)
+	if err != nil {
+		return timings, err
+	}
+	timings.IP = addrs[0].IP
+	// like the dialer, prefer IPv4 unless IPv6 connections are allowed
+	if p.config.IPNetwork != "ip6" && !p.config.DualStack {
+		for _, addr := range addrs {
+			if addr.IP.To4() != nil {
+				timings.IP = addr.IP
+				break
+			}
+		}
+	}
+
+	start = time.Now()
+	conn, err := p.client.Dial(net.JoinHostPort(timings.IP.String(), port))
+	timings.Connect = time.Since(start)
+	if err != nil {
+		return timings, err
+	}
+	defer conn.Close()
+
+	if parsed.Scheme != "https" {
+		return timings, nil
+	}
+
+	tlsConfig := p.client.TLSConfig.Clone()
+	tlsConfig.ServerName = parsed.Hostname()
+	tlsConn := tls.Client(conn, tlsConfig)
+	start = time.Now()
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return timings, err
+	}
+	timings.TLS = time.Since(start)
+
+	state := tlsConn.ConnectionState()
+	timings.TLSVersion = tls.VersionName(state.Version)
+	for _, certificate := range state.PeerCertificates {
+		if timings.CertificateExpiry.IsZero() || certificate.NotAfter.Before(timings.CertificateExpiry) {
+			timings.CertificateExpiry = certificate.NotAfter
+		}
+	}
+	return timings, nil
+}
@@ -0,0 +1,70 @@
+package probe
+
+import (
+	"net"
+
+	"github.com/valyala/fasthttp"
+)
+
+// pinnedClient returns a client that always dials addr, whatever the host of the requests it
+// sends, and uses serverName for SNI. Clients are cached so connections are reused.
+func (p *Prober) pinnedClient(addr string, isTLS bool, serverName string) *fasthttp.HostClient {
+	key := addr + "|" + serverName
+	if isTLS {
+		key = "tls|" + key
+	}
+
+	return p.pinnedClients.get(key, func() *fasthttp.HostClient {
+		client := &fasthttp.HostClient{
+			Addr:                          addr,
+			IsTLS:                         isTLS,
+			Dial:                          p.client.Dial,
+			ReadTimeout:                   p.client.ReadTimeout,
+			MaxIdleConnDuration:           p.client.MaxIdleConnDuration,
+			MaxConnDuration:               p.client.MaxConnDuration,
+			NoDefaultUserAgentHeader:      true,
+			DisableHeaderNamesNormalizing: true,
+			DisablePathNormalizing:        true,
+			MaxIdemponentCallAttempts:     1,
+			MaxResponseBodySize:           p.client.MaxResponseBodySize,
+		}
+		if isTLS {
+			client.TLSConfig = p.client.TLSConfig.Clone()
+			client.TLSConfig.ServerName = serverName
+		}
+		return client
+	})
+}
+
+// dialAddress adds the port of uri to a pinned address that has none
+func dialAddress(pinned string, uri *fasthttp.URI) string {
+	return pinnedAddress(pinned, fasthttp.AddMissingPort(string(uri.Host()), string(uri.Scheme()) == "https"))
+}
+
+// pinnedAddress returns the address to dial for addr (host:port): the pinned address with the
+// port of addr when it has none, or addr itself when nothing is pinned
+func pinnedAddress(pinned, addr string) string {
+	if pinned == "" {
+		return addr
+	}
+	if _, _, err := net.SplitHostPort(pinned); err == nil {
+		return pinned
+	}
+	_, port, _ := net.SplitHostPort(addr)
+	return net.JoinHostPort(pinned, port)
+}
+
+// doRequest sends req to the pinned address when there is one, or to the address its host resolves to.
+// Every request made for a target goes through it, so pinned targets are never reached another way.
+func (p *Prober) doRequest(pinned string, req *fasthttp.Request, resp *fasthttp.Response) error {
+	if pinned != "" {
+		return p.doPinnedRequest(pinned, req, resp)
+	}
+	return p.client.DoTimeout(req, resp, p.timeout())
+}
+
+func (p *Prober) doPinnedRequest(pinned string, req *fasthttp.Request, resp *fasthttp.Response) error {
+	uri := req.URI()
+	client := p.pinnedClient(dialAddress(pinned, uri), string(uri.Scheme()) == "https", hostnameOf(uri))
+	return client.DoTimeout(req, resp, p.timeout())
+}
@@ -0,0 +1,92 @@
+package probe
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+func TestPinnedTargetChecks(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+		if r.URL.Path == "/private" && !strings.HasPrefix(r.Header.Get("Authorization"), "Digest ") {
+			w.Header().Set("WWW-Authenticate", `Digest realm="test", nonce="abc", qop="auth"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, "<html><title>Pinned</title><body>Every path looks the same</body></html>")
+	})
+
+	plain := httptest.NewServer(h2c.NewHandler(handler, &http2.Server{}))
+	defer plain.Close()
+	_, plainPort, _ := net.SplitHostPort(plain.Listener.Addr().String())
+
+	tlsServer := httptest.NewUnstartedServer(handler)
+	tlsServer.EnableHTTP2 = true
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+	_, tlsPort, _ := net.SplitHostPort(tlsServer.Listener.Addr().String())
+
+	// the hostname doesn't resolve, so every request only succeeds through the pinned address
+	prober := NewProber(&ProberConfig{
+		URLs:           &[]string{},
+		Threads:        1,
+		Timeout:        5,
+		Method:         "GET",
+		Credentials:    &Credentials{Scheme: authDigest, Username: "user", Password: "secret"},
+		HTTP2:          true,
+		Soft404:        true,
+		CORS:           true,
+		MethodsScan:    true,
+		TLSScan:        true,
+		TLSFingerprint: true,
+	})
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	result := prober.probeURL(probeTarget{URL: "http://pinned.invalid:" + plainPort + "/private", DialAddr: "127.0.0.1"}, req, resp)
+	if result.StatusCode != http.StatusOK {
+		t.Fatalf("expected the Digest retry to reach the pinned address, got %+v", result)
+	}
+	if result.Protocol != protocolH2C {
+		t.Errorf("expected h2c to the pinned address, got %q", result.Protocol)
+	}
+	if !result.LikelySoft404 {
+		t.Error("expected the soft-404 baseline to be built from the pinned address")
+	}
+	if len(result.CORS) == 0 {
+		t.Error("expected CORS checks to reach the pinned address")
+	}
+	if result.Methods == nil || result.Methods.Statuses[fasthttp.MethodOptions] == 0 {
+		t.Errorf("expected the methods scan to reach the pinned address, got %+v", result.Methods)
+	}
+
+	result = prober.probeURL(probeTarget{URL: "https://pinned.invalid:" + tlsPort + "/", DialAddr: "127.0.0.1"}, req, resp)
+	if result.Protocol != protocolH2 || !slices.Equal(result.ALPN, []string{"h2"}) {
+		t.Errorf("expected h2 and its ALPN from the pinned address, got %q and %v", result.Protocol, result.ALPN)
+	}
+	if result.TLSScan == nil || len(result.TLSScan.Versions) == 0 {
+		t.Errorf("expected the TLS scan to reach the pinned address, got %+v", result.TLSScan)
+	}
+	if result.TLSFingerprint == nil || strings.Trim(result.TLSFingerprint.JARM, "0") == "" {
+		t.Errorf("expected a JARM fingerprint from the pinned address, got %+v", result.TLSFingerprint)
+	}
+	if names := prober.certificateNames("pinned.invalid:"+tlsPort, "127.0.0.1"); !slices.Contains(names, "example.com") {
+		t.Errorf("expected the certificate names of the pinned address, got %v", names)
+	}
+	if names := prober.certificateNames("pinned.invalid:"+tlsPort, ""); len(names) != 0 {
+		t.Errorf("expected nothing without the pinned address, got %v", names)
+	}
+}
@@ -3,14 +3,23 @@ package probe
 import (
 	"bufio"
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"iter"
 	"net"
 	"os"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/GraveSIN/http-probe/internal/scope"
+	"github.com/GraveSIN/http-probe/internal/secheaders"
+	"github.com/GraveSIN/http-probe/internal/source"
+	"github.com/GraveSIN/http-probe/internal/targets"
 	"github.com/GraveSIN/http-probe/internal/utils"
 	"github.com/GraveSIN/http-probe/internal/validator"
 	"github.com/spf13/cobra"
@@ -52,26 +61,98 @@ var defaultHeaders = map[string]string{
 
 // ProbeResult represents the result of an HTTP probe containing various response details
 type ProbeResult struct {
-	URL              string
-	StatusLine       string
-	ServerHeader     string
-	RedirectLocation string
-	Title            string
-	ContentType      string
-	ContentLength    int
-	PoweredByHeader  string
-	TimeTaken        time.Duration
+	URL                   string             `json:"url"`
+	BaseURL               string             `json:"base_url,omitempty"`
+	Path                  string             `json:"path,omitempty"`
+	StatusCode            int                `json:"status_code"`
+	StatusLine            string             `json:"status_line"`
+	ServerHeader          string             `json:"server_header,omitempty"`
+	RedirectLocation      string             `json:"redirect_location,omitempty"`
+	Title                 string             `json:"title,omitempty"`
+	ContentType           string             `json:"content_type,omitempty"`
+	ContentLength         int                `json:"content_length"`
+	PoweredByHeader       string             `json:"powered_by_header,omitempty"`
+	ContentSecurityPolicy string             `json:"content_security_policy,omitempty"`
+	TimeTaken             time.Duration      `json:"time_taken"`
+	LikelySoft404         bool               `json:"likely_soft_404,omitempty"`
+	Methods               *MethodScanResult  `json:"methods,omitempty"`
+	SecurityHeaders       *secheaders.Report `json:"security_headers,omitempty"`
+	CORS                  []CORSFinding      `json:"cors,omitempty"`
+	Cookies               []Cookie           `json:"cookies,omitempty"`
+	Technologies          []string           `json:"technologies,omitempty"`
+	Protocol              string             `json:"protocol,omitempty"`
+	ALPN                  []string           `json:"alpn,omitempty"`
+	AltSvc                []AltService       `json:"alt_svc,omitempty"`
+	HTTP3                 []HTTP3Result      `json:"http3,omitempty"`
+	TLSScan               *TLSScanResult     `json:"tls_scan,omitempty"`
+	TLSFingerprint        *TLSFingerprint    `json:"tls_fingerprint,omitempty"`
+	DiscoveredFrom        string             `json:"discovered_from,omitempty"`
+	// Skipped is the reason a target was not probed
+	Skipped string `json:"skipped,omitempty"`
+	// Error is why the request got no response. Failed requests are written in --json output, and
+	// otherwise only printed for the addresses of --all-ips, which have DialAddress set.
+	Error string `json:"error,omitempty"`
+	// DialAddress is the address the request was sent to when it differs from the URL's host
+	DialAddress string `json:"dial_address,omitempty"`
+	// CertificateSHA256 is set in --all-ips mode and with Hashes, IPDisagreements in --all-ips mode
+	CertificateSHA256 string   `json:"certificate_sha256,omitempty"`
+	IPDisagreements   []string `json:"ip_disagreements,omitempty"`
+	// ContentSHA256 is the hash of the decompressed body, set with Hashes
+	ContentSHA256 string `json:"content_sha256,omitempty"`
+	// IPv6Readiness is set in --dual-stack mode: ready, mismatch, unreachable or no-aaaa
+	IPv6Readiness string `json:"ipv6_readiness,omitempty"`
 }
 
 // ProberConfig contains the configuration options for the HTTP prober
 type ProberConfig struct {
-	URLs       *[]string
-	Threads    int
-	Timeout    int
-	Method     string
-	OutputFile string
-	Body       string
-	DNSMode    bool
+	URLs         *[]string
+	Paths        []string
+	Threads      int
+	Timeout      int
+	Method       string
+	MethodsScan  bool
+	ExtraMethods []string
+	OutputFile   string
+	JSONOutput   bool
+	Body         string
+	BodyBinary   bool
+	ContentType  string
+	Form         []FormField
+	RawRequest   *RawRequest
+	// Credentials apply to every input host without an entry in HostCredentials
+	Credentials     *Credentials
+	HostCredentials []hostCredentials
+	// AuthHTTPFallback lets requests with credentials fall back from HTTPS to HTTP, sending them in cleartext
+	AuthHTTPFallback  bool
+	ClientCertificate *tls.Certificate
+	RootCAs           *x509.CertPool
+	DNSMode           bool
+	Soft404           bool
+	SecurityHeaders   bool
+	CORS              bool
+	ShowCookies       bool
+	HTTP2             bool
+	HTTP3             bool
+	TLSScan           bool
+	TLSFingerprint    bool
+	Discover          bool
+	DiscoverDepth     int
+	// Scope is nil when no scope files are given
+	Scope *scope.Scope
+	// Expansion holds the address ranges expanded as the work pool drains, nil when there are none
+	Expansion *targets.Expansion
+	// VHosts are the hostnames sent to every target in vhost mode
+	VHosts []string
+	AllIPs bool
+	// ResolveOverrides pins hosts to addresses, nil when there are none
+	ResolveOverrides *resolveOverrides
+	// IPNetwork restricts connections to "ip4" or "ip6" addresses, "" allows both
+	IPNetwork string
+	DualStack bool
+	// Sources are the local addresses to send probes from, nil to let the system choose
+	Sources *source.Pool
+	// Hashes records the SHA-256 of every body and certificate, for callers tracking changes
+	Hashes bool
 }
 
 // Prober handles the HTTP probing operations
@@ -79,8 +160,27 @@ type Prober struct {
 	config    *ProberConfig
 	client    *fasthttp.Client
 	results   chan ProbeResult
-	workPool  chan string
+	workPool  chan probeTarget
 	waitGroup sync.WaitGroup
+	soft404   hostCache[[]responseFingerprint]
+	// http2 is only set when HTTP/2 is enabled
+	http2      *http2Clients
+	h2cUpgrade hostCache[bool]
+	alpn       hostCache[[]string]
+	tlsScans   hostCache[*TLSScanResult]
+	// http3 caches the QUIC handshake per advertised endpoint
+	http3 hostCache[HTTP3Result]
+	// tlsFingerprints caches the JARM and JA3S fingerprints per host
+	tlsFingerprints hostCache[*TLSFingerprint]
+	// discovery is only set when --discover is enabled
+	discovery    *discovery
+	certificates hostCache[[]string]
+	// pinnedClients send requests to a given address whatever their host
+	pinnedClients  hostCache[*fasthttp.HostClient]
+	vhostBaselines hostCache[[]responseFingerprint]
+	resolver       fasthttp.Resolver
+	// pinnedHTTP2 holds the HTTP/2 transports dialing a pinned address
+	pinnedHTTP2 hostCache[*http2Clients]
 }
 
 func ParseHTTPProbeConfig(cmd *cobra.Command) (*ProberConfig, error) {
@@ -89,9 +189,49 @@ func ParseHTTPProbeConfig(cmd *cobra.Command) (*ProberConfig, error) {
 	method, _ := cmd.Flags().GetString("method")
 	threads, _ := cmd.Flags().GetInt("threads")
 	output, _ := cmd.Flags().GetString("output")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
 	body, _ := cmd.Flags().GetString("data")
+	bodyBinary, _ := cmd.Flags().GetString("data-binary")
+	bodyContentType, _ := cmd.Flags().GetString("content-type")
+	formArgs, _ := cmd.Flags().GetStringArray("form")
+	rawRequestFile, _ := cmd.Flags().GetString("raw-request")
+	auth, _ := cmd.Flags().GetString("auth")
+	bearer, _ := cmd.Flags().GetString("bearer")
+	authFile, _ := cmd.Flags().GetString("auth-file")
+	authHTTPFallback, _ := cmd.Flags().GetBool("auth-http-fallback")
+	scopeFile, _ := cmd.Flags().GetString("scope")
+	outOfScopeFile, _ := cmd.Flags().GetString("out-of-scope")
+	ports, _ := cmd.Flags().GetStringSlice("ports")
+	exclude, _ := cmd.Flags().GetStringSlice("exclude")
+	asnFile, _ := cmd.Flags().GetString("asn-file")
+	vhostFile, _ := cmd.Flags().GetString("vhost")
+	allIPs, _ := cmd.Flags().GetBool("all-ips")
+	resolve, _ := cmd.Flags().GetStringArray("resolve")
+	hostsFile, _ := cmd.Flags().GetString("hosts-file")
+	ipv4, _ := cmd.Flags().GetBool("ipv4")
+	ipv6, _ := cmd.Flags().GetBool("ipv6")
+	dualStack, _ := cmd.Flags().GetBool("dual-stack")
+	sourceIPs, _ := cmd.Flags().GetStringSlice("source-ip")
+	iface, _ := cmd.Flags().GetString("interface")
+	certFile, _ := cmd.Flags().GetString("cert")
+	keyFile, _ := cmd.Flags().GetString("key")
+	caFile, _ := cmd.Flags().GetString("cacert")
 	timeout, _ := cmd.Flags().GetInt("timeout")
 	dnsMode, _ := cmd.Flags().GetBool("dns")
+	soft404, _ := cmd.Flags().GetBool("soft-404")
+	securityHeaders, _ := cmd.Flags().GetBool("security-headers")
+	cors, _ := cmd.Flags().GetBool("cors")
+	showCookies, _ := cmd.Flags().GetBool("cookies")
+	http2, _ := cmd.Flags().GetBool("http2")
+	http3, _ := cmd.Flags().GetBool("http3")
+	tlsScan, _ := cmd.Flags().GetBool("tls-scan")
+	tlsFingerprint, _ := cmd.Flags().GetBool("tls-fingerprint")
+	discover, _ := cmd.Flags().GetBool("discover")
+	discoverDepth, _ := cmd.Flags().GetInt("discover-depth")
+	paths, _ := cmd.Flags().GetStringSlice("path")
+	pathFile, _ := cmd.Flags().GetString("paths")
+	methodsScan, _ := cmd.Flags().GetBool("methods-scan")
+	extraMethods, _ := cmd.Flags().GetStringSlice("extra-methods")
 
 	// URLs from file
 	if urlFile != "" {
@@ -120,6 +260,30 @@ func ParseHTTPProbeConfig(cmd *cobra.Command) (*ProberConfig, error) {
 		os.Exit(1)
 	}
 
+	// CIDR prefixes, IP ranges and AS numbers are expanded lazily by the work pool
+	var asnPrefixes targets.ASNPrefixes
+	if asnFile != "" {
+		var err error
+		if asnPrefixes, err = targets.ReadASNFile(asnFile); err != nil {
+			return nil, err
+		}
+	}
+	urls, ranges, err := targets.Split(urls, asnPrefixes)
+	if err != nil {
+		return nil, err
+	}
+	if ports, err = targets.ParsePorts(ports); err != nil {
+		return nil, err
+	}
+	var expansion *targets.Expansion
+	if len(ranges) > 0 {
+		excluded, err := targets.ParseExclusions(exclude)
+		if err != nil {
+			return nil, err
+		}
+		expansion = &targets.Expansion{Ranges: ranges, Excluded: excluded, Ports: ports}
+	}
+
 	// validate URLs and return errors
 
 	validURLs, err := validator.ConvertDomainsToURLsAndReturnValidURLs(&urls)
@@ -127,20 +291,145 @@ func ParseHTTPProbeConfig(cmd *cobra.Command) (*ProberConfig, error) {
 		fmt.Println(err)
 		os.Exit(1)
 	}
+	validURLs = targets.WithPorts(validURLs, ports)
 
-	if len(validURLs) == 0 {
+	if len(validURLs) == 0 && expansion == nil {
 		fmt.Println("[!] no valid URLs found")
 		os.Exit(1)
 	}
 
+	// Request body
+	if body != "" && bodyBinary != "" {
+		return nil, fmt.Errorf("[!] -d and --data-binary can't be used together")
+	}
+	if bodyBinary != "" {
+		body = bodyBinary
+	}
+	body, err = readBodyArgument(body)
+	if err != nil {
+		return nil, err
+	}
+	if body != "" && len(formArgs) > 0 {
+		return nil, fmt.Errorf("[!] -F can't be combined with -d or --data-binary")
+	}
+	form, err := parseFormFields(formArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Raw request template
+	var rawRequest *RawRequest
+	if rawRequestFile != "" {
+		rawRequest, err = ReadRawRequest(rawRequestFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Authentication
+	var credentials *Credentials
+	switch {
+	case auth != "" && bearer != "":
+		return nil, fmt.Errorf("[!] --auth and --bearer can't be used together")
+	case auth != "":
+		if credentials, err = ParseCredentials(auth); err != nil {
+			return nil, err
+		}
+	case bearer != "":
+		credentials = &Credentials{Scheme: authBearer, Token: bearer}
+	}
+
+	var hostCredentials []hostCredentials
+	if authFile != "" {
+		if hostCredentials, err = readCredentialsFile(authFile); err != nil {
+			return nil, err
+		}
+	}
+
+	clientCertificate, rootCAs, err := loadClientTLS(certFile, keyFile, caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	targetScope, err := scope.Load(scopeFile, outOfScopeFile)
+	if err != nil {
+		return nil, err
+	}
+
+	resolveOverrides, err := parseResolveOverrides(resolve, hostsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	ipNetwork, err := utils.IPNetwork(ipv4, ipv6, dualStack)
+	if err != nil {
+		return nil, err
+	}
+	if allIPs && dualStack {
+		return nil, fmt.Errorf("[!] --all-ips and --dual-stack can't be used together")
+	}
+
+	sources, err := source.New(sourceIPs, iface)
+	if err != nil {
+		return nil, err
+	}
+
+	// Virtual host wordlist
+	var vhosts []string
+	if vhostFile != "" {
+		if vhosts, err = utils.ReadURLsFromFile(vhostFile); err != nil {
+			return nil, err
+		}
+	}
+
+	// Paths from file
+	if pathFile != "" {
+		pathsFromFile, err := utils.ReadURLsFromFile(pathFile)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, pathsFromFile...)
+	}
+
 	return &ProberConfig{
-		URLs:       &validURLs,
-		Method:     method,
-		Threads:    threads,
-		OutputFile: output,
-		Body:       body,
-		DNSMode:    dnsMode,
-		Timeout:    int(timeout),
+		URLs:              &validURLs,
+		Paths:             paths,
+		Method:            method,
+		MethodsScan:       methodsScan,
+		ExtraMethods:      extraMethods,
+		Threads:           threads,
+		OutputFile:        output,
+		JSONOutput:        jsonOutput,
+		Body:              body,
+		BodyBinary:        bodyBinary != "",
+		ContentType:       bodyContentType,
+		Form:              form,
+		RawRequest:        rawRequest,
+		Credentials:       credentials,
+		HostCredentials:   hostCredentials,
+		AuthHTTPFallback:  authHTTPFallback,
+		ClientCertificate: clientCertificate,
+		RootCAs:           rootCAs,
+		DNSMode:           dnsMode,
+		Timeout:           int(timeout),
+		Soft404:           soft404,
+		SecurityHeaders:   securityHeaders,
+		CORS:              cors,
+		ShowCookies:       showCookies,
+		HTTP2:             http2,
+		HTTP3:             http3,
+		TLSScan:           tlsScan,
+		TLSFingerprint:    tlsFingerprint,
+		Discover:          discover,
+		DiscoverDepth:     discoverDepth,
+		Scope:             targetScope,
+		Expansion:         expansion,
+		VHosts:            vhosts,
+		AllIPs:            allIPs,
+		ResolveOverrides:  resolveOverrides,
+		IPNetwork:         ipNetwork,
+		DualStack:         dualStack,
+		Sources:           sources,
 	}, nil
 }
 
@@ -150,24 +439,73 @@ func NewProber(config *ProberConfig) *Prober {
 	urlCount := len(*config.URLs)
 	bufferSize := config.Threads * 2
 
-	return &Prober{
+	prober := &Prober{
 		config:   config,
 		client:   createOptimizedClient(config),
+		resolver: newResolver(config),
 		results:  make(chan ProbeResult, bufferSize),
-		workPool: make(chan string, urlCount),
+		workPool: make(chan probeTarget, urlCount),
+	}
+	if config.HTTP2 {
+		prober.http2 = newHTTP2Clients(prober.client, "")
+	}
+	if config.Discover {
+		prober.discovery = newDiscovery(*config.URLs, config.DiscoverDepth, config.Scope)
 	}
+
+	return prober
 }
 
 // createOptimizedClient creates a fasthttp.Client with optimized settings for HTTP probing
 func createOptimizedClient(config *ProberConfig) *fasthttp.Client {
 	dialer := &fasthttp.TCPDialer{
-		Resolver: &net.Resolver{
-			PreferGo: true,
-			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
-				d := net.Dialer{}
-				return d.DialContext(ctx, "udp", "1.1.1.1:53")
-			},
-		},
+		Resolver: newResolver(config),
+	}
+
+	// fasthttp only dials IPv4 addresses unless dialing dual stack, so IPv6 literals (expanded
+	// targets, pinned addresses) are always dialed dual stack
+	baseDial := func(addr string) (net.Conn, error) {
+		if isIPv6Literal(addr) {
+			return dialer.DialDualStack(addr)
+		}
+		return dialer.Dial(addr)
+	}
+	if config.IPNetwork == "ip6" || config.DualStack {
+		baseDial = dialer.DialDualStack
+	}
+	if config.Sources != nil {
+		baseDial = sourceDial(dialer.Resolver, config)
+	}
+
+	dial := baseDial
+	if config.Scope != nil || config.ResolveOverrides != nil {
+		dial = func(addr string) (net.Conn, error) {
+			host, _, _ := net.SplitHostPort(addr)
+			// only the dialed address changes, TLS keeps the original host for SNI
+			if config.ResolveOverrides != nil {
+				addr = config.ResolveOverrides.dialAddr(addr)
+			}
+			// resolved addresses are checked by the resolver, IP literals are never resolved so check them here
+			if config.Scope != nil {
+				dialHost, _, err := net.SplitHostPort(addr)
+				if ip := net.ParseIP(dialHost); err == nil && ip != nil {
+					if err := config.Scope.CheckAddress(host, ip); err != nil {
+						return nil, err
+					}
+				}
+			}
+			return baseDial(addr)
+		}
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: true}
+	if config.ClientCertificate != nil {
+		tlsConfig.Certificates = []tls.Certificate{*config.ClientCertificate}
+	}
+	if config.RootCAs != nil {
+		// a CA bundle is only given to verify servers against it
+		tlsConfig.RootCAs = config.RootCAs
+		tlsConfig.InsecureSkipVerify = false
 	}
 
 	timeout := time.Duration(config.Timeout) * time.Second
@@ -182,9 +520,68 @@ func createOptimizedClient(config *ProberConfig) *fasthttp.Client {
 		MaxConnDuration:               time.Minute,
 		MaxIdemponentCallAttempts:     1,
 		MaxResponseBodySize:           10 * 1024 * 1024,
-		TLSConfig:                     &tls.Config{InsecureSkipVerify: true},
-		Dial:                          dialer.Dial,
+		TLSConfig:                     tlsConfig,
+		Dial:                          dial,
+	}
+}
+
+// sourceDial returns a dial func sending connections from the source address pool in round-robin,
+// which fasthttp's TCPDialer can't do as it binds a single local address
+func sourceDial(resolver fasthttp.Resolver, config *ProberConfig) fasthttp.DialFunc {
+	dualStack := config.IPNetwork == "ip6" || config.DualStack
+	timeout := time.Duration(config.Timeout) * time.Second
+
+	return func(addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		ips, err := resolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		err = fmt.Errorf("no IPv4 address for %s", host)
+		for _, ip := range ips {
+			// like fasthttp, only IPv4 addresses are dialed unless dialing dual stack or an IPv6 literal
+			if !dualStack && ip.IP.To4() == nil && net.ParseIP(host) == nil {
+				continue
+			}
+			dialer, dialErr := config.Sources.Dialer("tcp", ip.IP, timeout)
+			if dialErr != nil {
+				err = dialErr
+				continue
+			}
+			conn, dialErr := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip.IP.String(), port))
+			if dialErr == nil {
+				return conn, nil
+			}
+			err = dialErr
+		}
+		return nil, err
+	}
+}
+
+// newResolver creates the resolver used by the dialer. It answers from the hosts file overrides first,
+// then keeps the addresses of the chosen IP family and drops out of scope ones when a scope is set.
+func newResolver(config *ProberConfig) fasthttp.Resolver {
+	var resolver fasthttp.Resolver = &net.Resolver{
+		PreferGo: true,
+		Dial:     config.Sources.ResolverDial("1.1.1.1:53", 0),
+	}
+	if config.ResolveOverrides != nil {
+		resolver = &overrideResolver{resolver: resolver, overrides: config.ResolveOverrides}
 	}
+	if config.IPNetwork != "" {
+		resolver = &familyResolver{resolver: resolver, network: config.IPNetwork}
+	}
+	if config.Scope != nil {
+		return &scope.Resolver{Resolver: resolver, Scope: config.Scope}
+	}
+	return resolver
 }
 
 // Start begins the probing process by initializing workers and returning a channel for results
@@ -202,12 +599,77 @@ func (p *Prober) Start() chan ProbeResult {
 	return p.results
 }
 
-// initializeWorkPool populates the work pool with URLs to be processed
+// initializeWorkPool populates the work pool with URLs to be processed.
+// When paths are configured, every URL is combined with every path as the pool drains,
+// so the full host x path product is never held in memory
 func (p *Prober) initializeWorkPool() {
-	for _, url := range *p.config.URLs {
-		p.workPool <- url
+	defer close(p.workPool)
+
+	stopFeeder := make(chan struct{})
+	feederDone := make(chan struct{})
+	if p.discovery != nil {
+		go func() {
+			defer close(feederDone)
+			p.discovery.feed(p.workPool, stopFeeder)
+		}()
+	}
+
+	switch {
+	case len(p.config.VHosts) > 0:
+		// iterate hostnames in the outer loop to spread consecutive requests across addresses
+		for _, hostname := range p.config.VHosts {
+			for baseURL := range p.baseURLs() {
+				if target, err := newVHostTarget(baseURL, hostname); err == nil {
+					p.queue(target)
+				}
+			}
+		}
+	case len(p.config.Paths) == 0:
+		for url := range p.baseURLs() {
+			p.queue(probeTarget{URL: url})
+		}
+	default:
+		// iterate paths in the outer loop to spread consecutive requests across hosts
+		for _, path := range p.config.Paths {
+			for baseURL := range p.baseURLs() {
+				p.queue(newPathTarget(baseURL, path))
+			}
+		}
+	}
+
+	// discovered targets are queued by the workers, so keep the pool open until every target is probed.
+	// The discovery queue is empty by then, so the feeder is idle and can stop before the pool closes.
+	if p.discovery != nil {
+		p.discovery.pending.Wait()
+		close(stopFeeder)
+		<-feederDone
+	}
+}
+
+// baseURLs yields the input URLs, then the addresses of the expanded ranges
+func (p *Prober) baseURLs() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for _, url := range *p.config.URLs {
+			if !yield(url) {
+				return
+			}
+		}
+		if p.config.Expansion != nil {
+			for url := range p.config.Expansion.URLs() {
+				if !yield(url) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// queue adds a seed target to the work pool
+func (p *Prober) queue(target probeTarget) {
+	if p.discovery != nil {
+		p.discovery.pending.Add(1)
 	}
-	close(p.workPool)
+	p.workPool <- target
 }
 
 // worker processes URLs from the work pool until the pool is empty
@@ -219,17 +681,58 @@ func (p *Prober) worker() {
 	defer fasthttp.ReleaseRequest(req)
 	defer fasthttp.ReleaseResponse(resp)
 
-	for url := range p.workPool {
-		result := p.probeURL(url, req, resp)
-		p.results <- result
+	for target := range p.workPool {
+		for _, result := range p.probeTarget(target, req, resp) {
+			p.results <- result
+		}
+		if p.discovery != nil {
+			p.discovery.pending.Done()
+		}
+	}
+}
+
+// Probe probes a single URL outside of the work pool, for callers that probe targets one at a time
+// instead of through Start
+func (p *Prober) Probe(url string) []ProbeResult {
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	return p.probeTarget(probeTarget{URL: url}, req, resp)
+}
+
+// probeTarget probes target once, or once per address in --all-ips and --dual-stack modes
+func (p *Prober) probeTarget(target probeTarget, req *fasthttp.Request, resp *fasthttp.Response) []ProbeResult {
+	switch {
+	case p.config.AllIPs:
+		return p.probeAllIPs(target, req, resp)
+	case p.config.DualStack:
+		return p.probeDualStack(target, req, resp)
 	}
+	return []ProbeResult{p.probeURL(target, req, resp)}
 }
 
-// probeURL performs an HTTP request to the specified URL and returns the probe results
-func (p *Prober) probeURL(url string, req *fasthttp.Request, resp *fasthttp.Response) ProbeResult {
+// probeURL performs an HTTP request to the specified target and returns the probe results
+func (p *Prober) probeURL(target probeTarget, req *fasthttp.Request, resp *fasthttp.Response) ProbeResult {
+	url := target.URL
 	req.Reset()
 	resp.Reset()
 
+	if p.config.Scope != nil {
+		if err := p.config.Scope.CheckHost(urlHostname(url)); err != nil {
+			return skippedResult(target, err)
+		}
+	}
+
+	if p.config.RawRequest != nil {
+		startTime := time.Now()
+		if err := p.doRawRequest(target, req, resp); err != nil {
+			return failedResult(target, err)
+		}
+		return p.completeProbeResult(target, string(req.URI().FullURI()), req, resp, startTime)
+	}
+
 	req.SetRequestURI(url)
 	req.Header.SetMethod(p.config.Method)
 
@@ -237,22 +740,109 @@ func (p *Prober) probeURL(url string, req *fasthttp.Request, resp *fasthttp.Resp
 		req.Header.Set(key, value)
 	}
 
-	if p.config.Body != "" {
-		req.SetBodyString(p.config.Body)
-		bodyLen := len(p.config.Body)
-		req.Header.SetContentLength(bodyLen)
+	if err := p.setRequestBody(req, url); err != nil {
+		return failedResult(target, fmt.Errorf("error building the request body: %w", err))
+	}
 
-		if bodyLen > 0 {
-			req.Header.Set("Content-Type", detectContentType(p.config.Body[0], p.config.Body))
-		}
+	credentials := p.credentialsFor(target, string(req.URI().Host()))
+	if credentials != nil {
+		setAuthorization(req, credentials)
 	}
+	fallback := p.allowHTTPFallback(credentials != nil)
 
 	startTime := time.Now()
-	if err := p.makeRequest(req, resp, url); err != nil {
+	protocol, err := p.makeRequest(req, resp, url, target.DialAddr, fallback)
+	if err != nil {
+		return failedResult(target, err)
+	}
+	// the result reports the URL that answered, so a fallback shows up as plain HTTP
+	if strings.HasPrefix(url, "https://") && string(req.URI().Scheme()) == "http" {
+		url = "http://" + strings.TrimPrefix(url, "https://")
+	}
+
+	if credentials != nil && resp.StatusCode() == fasthttp.StatusUnauthorized {
+		if err := p.authenticate(req, resp, credentials, target.DialAddr); err != nil {
+			return failedResult(target, err)
+		}
+	}
+
+	if len(p.config.VHosts) > 0 && p.isDefaultVHost(target, req, resp) {
 		return ProbeResult{}
 	}
 
-	return createProbeResult(url, resp, startTime, p)
+	result := p.completeProbeResult(target, url, req, resp, startTime)
+	result.Protocol = protocol
+	result.DialAddress = target.DialAddr
+	return result
+}
+
+// completeProbeResult builds the result for a finished request and runs the optional per-response checks
+func (p *Prober) completeProbeResult(target probeTarget, url string, req *fasthttp.Request, resp *fasthttp.Response, startTime time.Time) ProbeResult {
+	result := createProbeResult(url, resp, startTime, p)
+	result.BaseURL = target.BaseURL
+	result.Path = target.Path
+
+	// the checks below reuse req, so keep what they need from the request that was answered
+	uri := req.URI()
+	effectiveURL := string(uri.FullURI())
+	isHTTPS := string(uri.Scheme()) == "https"
+	result.Cookies = parseCookies(resp, hostnameOf(uri), isHTTPS)
+	result.Technologies = cookieTechnologies(result.Cookies)
+
+	// the checks sending requests of their own authenticate like the probe, but only send the
+	// credentials over plain HTTP when --auth-http-fallback allows it
+	var credentials *Credentials
+	if isHTTPS || p.config.AuthHTTPFallback {
+		credentials = p.credentialsFor(target, string(uri.Host()))
+	}
+
+	result.DiscoveredFrom = target.DiscoveredFrom
+	if p.discovery != nil {
+		p.discoverHostnames(target, uri, resp, result)
+	}
+
+	if isHTTPS {
+		// listing the accepted protocols costs one handshake per protocol, once per host and address
+		result.ALPN = p.negotiatedALPN(string(uri.Host()), target.DialAddr)
+		if p.config.TLSScan {
+			result.TLSScan = p.scanTLS(string(uri.Host()), target.DialAddr)
+		}
+		if p.config.TLSFingerprint {
+			result.TLSFingerprint = p.fingerprintTLS(string(uri.Host()), target.DialAddr)
+		}
+	}
+
+	result.AltSvc = parseAltSvc(string(resp.Header.Peek("Alt-Svc")))
+	if p.config.HTTP3 {
+		result.HTTP3 = p.probeHTTP3(hostnameOf(uri), target.DialAddr, result.AltSvc)
+	}
+
+	if p.config.Soft404 {
+		result.LikelySoft404 = p.isLikelySoft404(target.DialAddr, credentials, req, resp)
+	}
+	if p.config.SecurityHeaders {
+		result.SecurityHeaders = secheaders.Audit(func(name string) string {
+			return string(resp.Header.Peek(name))
+		}, isHTTPS)
+	}
+	if p.config.CORS {
+		result.CORS = p.checkCORS(effectiveURL, target.DialAddr, credentials, req, resp)
+	}
+	if p.config.MethodsScan {
+		result.Methods = p.scanMethods(effectiveURL, target.DialAddr, credentials, req, resp)
+	}
+	if p.config.Hashes {
+		result.ContentSHA256 = contentFingerprint(resp)
+		if isHTTPS {
+			addr := fasthttp.AddMissingPort(string(uri.Host()), true)
+			if target.DialAddr != "" {
+				addr = dialAddress(target.DialAddr, uri)
+			}
+			result.CertificateSHA256 = p.certificateFingerprint(addr, hostnameOf(uri))
+		}
+	}
+
+	return result
 }
 
 // detectContentType determines the appropriate Content-Type header based on the request body
@@ -270,17 +860,68 @@ func detectContentType(firstByte byte, body string) string {
 	}
 }
 
-// makeRequest performs the HTTP request with fallback to HTTP if HTTPS fails
-func (p *Prober) makeRequest(req *fasthttp.Request, resp *fasthttp.Response, url string) error {
-	if err := p.client.DoTimeout(req, resp, time.Duration(p.config.Timeout)*time.Second); err != nil {
-		if strings.HasPrefix(url, "https://") {
-			url = "http://" + url[8:]
-			req.SetRequestURI(url)
-			return p.client.DoTimeout(req, resp, time.Duration(p.config.Timeout))
+// makeRequest performs the HTTP request with fallback to HTTP if HTTPS fails and fallback is set,
+// and returns the protocol used. pinned, when set, is dialed instead of the address the host resolves to.
+func (p *Prober) makeRequest(req *fasthttp.Request, resp *fasthttp.Response, url, pinned string, fallback bool) (string, error) {
+	send := func() (string, error) {
+		if p.http2 != nil {
+			return p.doHTTP2Request(req, resp, pinned)
+		}
+		if err := p.doRequest(pinned, req, resp); err != nil {
+			return "", err
 		}
-		return err
+		return string(resp.Header.Protocol()), nil
+	}
+
+	protocol, err := send()
+	var blocked *scope.BlockedError
+	if err != nil && fallback && strings.HasPrefix(url, "https://") && !errors.As(err, &blocked) {
+		p.fallbackToHTTP(req)
+		return send()
+	}
+	return protocol, err
+}
+
+// allowHTTPFallback reports whether a request that failed over HTTPS may be retried over plain HTTP.
+// Requests with credentials or a client certificate are only retried with --auth-http-fallback, so
+// whatever makes the TLS connection fail never receives the credentials in cleartext.
+func (p *Prober) allowHTTPFallback(withCredentials bool) bool {
+	return p.config.AuthHTTPFallback || (!withCredentials && p.config.ClientCertificate == nil)
+}
+
+// fallbackToHTTP switches req to plain HTTP after HTTPS failed, dropping its Authorization header
+// unless --auth-http-fallback is set
+func (p *Prober) fallbackToHTTP(req *fasthttp.Request) {
+	req.URI().SetScheme("http")
+	if !p.config.AuthHTTPFallback {
+		req.Header.Del("Authorization")
+	}
+}
+
+// skippedResult reports a target that was not probed because it is out of scope
+func skippedResult(target probeTarget, err error) ProbeResult {
+	return ProbeResult{
+		URL:            target.URL,
+		BaseURL:        target.BaseURL,
+		Path:           target.Path,
+		DiscoveredFrom: target.DiscoveredFrom,
+		Skipped:        err.Error(),
+	}
+}
+
+// failedResult reports a request that got no response, as skipped when its host resolved out of scope
+func failedResult(target probeTarget, err error) ProbeResult {
+	var blocked *scope.BlockedError
+	if errors.As(err, &blocked) {
+		return skippedResult(target, blocked)
+	}
+	return ProbeResult{
+		URL:            target.URL,
+		BaseURL:        target.BaseURL,
+		Path:           target.Path,
+		DiscoveredFrom: target.DiscoveredFrom,
+		Error:          err.Error(),
 	}
-	return nil
 }
 
 // createProbeResult constructs a ProbeResult struct from the HTTP response
@@ -296,18 +937,35 @@ func createProbeResult(url string, resp *fasthttp.Response, startTime time.Time,
 
 	return ProbeResult{
 		URL:        url,
+		StatusCode: resp.StatusCode(),
 		StatusLine: fmt.Sprintf("%d %s", resp.StatusCode(), fasthttp.StatusMessage(resp.StatusCode())),
 
-		ServerHeader:     string(resp.Header.Peek("Server")),
-		ContentType:      contentType,
-		RedirectLocation: string(resp.Header.Peek("Location")),
-		Title:            utils.GetHTTPTitleFromBody(resp.Body()),
-		ContentLength:    contentLength,
-		PoweredByHeader:  string(resp.Header.Peek("X-Powered-By")),
-		TimeTaken:        time.Since(startTime),
+		ServerHeader:          string(resp.Header.Peek("Server")),
+		ContentType:           contentType,
+		RedirectLocation:      string(resp.Header.Peek("Location")),
+		Title:                 utils.GetHTTPTitleFromBody(resp.Body()),
+		ContentLength:         contentLength,
+		PoweredByHeader:       string(resp.Header.Peek("X-Powered-By")),
+		ContentSecurityPolicy: string(resp.Header.Peek("Content-Security-Policy")),
+		TimeTaken:             time.Since(startTime),
 	}
 }
 
+// contentFingerprint returns the SHA-256 of the decompressed body, so compression doesn't change it
+func contentFingerprint(resp *fasthttp.Response) string {
+	body, err := resp.BodyUncompressed()
+	if err != nil {
+		body = resp.Body()
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// timeout returns the configured per-request timeout
+func (p *Prober) timeout() time.Duration {
+	return time.Duration(p.config.Timeout) * time.Second
+}
+
 // waitAndClose waits for all workers to complete their tasks and closes the results channel
 func (p *Prober) waitAndClose() {
 	p.waitGroup.Wait()
@@ -0,0 +1,192 @@
+package probe
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// RawRequest is a Burp-style request template replayed against every target
+type RawRequest struct {
+	Method string
+	Path   string
+	Proto  string
+	// Headers keep the exact casing and order of the template
+	Headers [][2]string
+	Body    string
+}
+
+// ReadRawRequest parses a raw HTTP request (request line, headers, blank line, body) from a file
+func ReadRawRequest(file string) (*RawRequest, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading raw request %s: %w", file, err)
+	}
+
+	return parseRawRequest(string(content))
+}
+
+func parseRawRequest(content string) (*RawRequest, error) {
+	content = strings.TrimLeft(content, "\r\n")
+	head, body, found := strings.Cut(content, "\r\n\r\n")
+	if !found {
+		head, body, _ = strings.Cut(content, "\n\n")
+	}
+
+	lines := strings.Split(strings.ReplaceAll(head, "\r\n", "\n"), "\n")
+	requestLine := strings.Fields(lines[0])
+	if len(requestLine) != 3 {
+		return nil, fmt.Errorf("invalid raw request line: %q", lines[0])
+	}
+
+	raw := &RawRequest{
+		Method: requestLine[0],
+		Path:   requestLine[1],
+		Proto:  requestLine[2],
+		Body:   body,
+	}
+
+	// requests are replayed over HTTP/1.x connections, so HTTP/2 exports from Burp are sent as HTTP/1.1
+	if raw.Proto != "HTTP/1.0" {
+		raw.Proto = "HTTP/1.1"
+	}
+
+	// absolute-form request targets keep only their path
+	if idx := strings.Index(raw.Path, "://"); idx >= 0 {
+		raw.Path = "/"
+		if slash := strings.Index(requestLine[1][idx+3:], "/"); slash >= 0 {
+			raw.Path = requestLine[1][idx+3+slash:]
+		}
+	}
+
+	for _, line := range lines[1:] {
+		if line == "" {
+			continue
+		}
+		name, value, found := strings.Cut(line, ":")
+		if !found {
+			return nil, fmt.Errorf("invalid raw request header: %q", line)
+		}
+		raw.Headers = append(raw.Headers, [2]string{name, strings.TrimLeft(value, " \t")})
+	}
+
+	return raw, nil
+}
+
+// hasHeader reports whether the template sets the header name
+func (r *RawRequest) hasHeader(name string) bool {
+	for _, header := range r.Headers {
+		if strings.EqualFold(header[0], name) {
+			return true
+		}
+	}
+	return false
+}
+
+// build renders the template for the given host, replacing Host and recomputing Content-Length
+func (r *RawRequest) build(path, host, url string) []byte {
+	body := expandTemplate(r.Body, url)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s %s %s\r\n", r.Method, expandTemplate(path, url), r.Proto)
+
+	hasHost, hasLength, chunked := false, false, false
+	for _, header := range r.Headers {
+		name, value := header[0], expandTemplate(header[1], url)
+		switch strings.ToLower(name) {
+		case "host":
+			value = host
+			hasHost = true
+		case "content-length":
+			value = strconv.Itoa(len(body))
+			hasLength = true
+		case "transfer-encoding":
+			chunked = strings.Contains(strings.ToLower(value), "chunked")
+		}
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+
+	if !hasHost {
+		fmt.Fprintf(&buf, "Host: %s\r\n", host)
+	}
+	if !hasLength && !chunked && len(body) > 0 {
+		fmt.Fprintf(&buf, "Content-Length: %d\r\n", len(body))
+	}
+
+	buf.WriteString("\r\n")
+	buf.WriteString(body)
+	return buf.Bytes()
+}
+
+// doRawRequest replays the raw request template against the target, falling back from HTTPS to HTTP
+// unless the template carries an Authorization header
+func (p *Prober) doRawRequest(target probeTarget, req *fasthttp.Request, resp *fasthttp.Response) error {
+	path := p.config.RawRequest.Path
+	if target.Path != "" {
+		path = target.Path
+	}
+
+	uri := fasthttp.AcquireURI()
+	defer fasthttp.ReleaseURI(uri)
+	if err := uri.Parse(nil, []byte(target.URL)); err != nil {
+		return err
+	}
+
+	err := p.sendRawRequest(string(uri.Scheme()), string(uri.Host()), path, target.DialAddr, req, resp)
+	if err != nil && string(uri.Scheme()) == "https" && p.allowHTTPFallback(p.config.RawRequest.hasHeader("Authorization")) {
+		err = p.sendRawRequest("http", string(uri.Host()), path, target.DialAddr, req, resp)
+	}
+	return err
+}
+
+// sendRawRequest writes the rendered template on a fresh connection and reads the response.
+// req is only used to record the effective URL of the replayed request.
+func (p *Prober) sendRawRequest(scheme, host, path, pinned string, req *fasthttp.Request, resp *fasthttp.Response) error {
+	url := scheme + "://" + host + path
+	req.SetRequestURI(url)
+	req.Header.SetMethod(p.config.RawRequest.Method)
+	resp.Reset()
+
+	conn, err := p.dialTarget(scheme, host, pinned)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write(p.config.RawRequest.build(path, host, url)); err != nil {
+		return err
+	}
+
+	resp.SkipBody = p.config.RawRequest.Method == fasthttp.MethodHead
+	return resp.ReadLimitBody(bufio.NewReader(conn), p.client.MaxResponseBodySize)
+}
+
+// dialTarget opens a dedicated connection to host, or to the pinned address when set, with the
+// client's dialer and TLS settings, for exchanges that must control the connection themselves
+func (p *Prober) dialTarget(scheme, host, pinned string) (net.Conn, error) {
+	addr := fasthttp.AddMissingPort(host, scheme == "https")
+	conn, err := p.client.Dial(pinnedAddress(pinned, addr))
+	if err != nil {
+		return nil, err
+	}
+
+	if scheme == "https" {
+		tlsConfig := p.client.TLSConfig.Clone()
+		tlsConfig.ServerName, _, _ = net.SplitHostPort(addr)
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	if err := conn.SetDeadline(time.Now().Add(p.timeout())); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
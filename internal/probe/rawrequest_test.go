@@ -0,0 +1,86 @@
+package probe
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseRawRequest(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		want    RawRequest
+	}{
+		{
+			name:    "CRLF",
+			content: "POST /api/login HTTP/1.1\r\nHost: example.com\r\nX-Token:  abc\r\n\r\nuser=admin",
+			want: RawRequest{
+				Method:  "POST",
+				Path:    "/api/login",
+				Proto:   "HTTP/1.1",
+				Headers: [][2]string{{"Host", "example.com"}, {"X-Token", "abc"}},
+				Body:    "user=admin",
+			},
+		},
+		{
+			name:    "LF and absolute-form target",
+			content: "\nGET https://example.com/admin?x=1 HTTP/1.0\nAccept: */*\n\n",
+			want: RawRequest{
+				Method:  "GET",
+				Path:    "/admin?x=1",
+				Proto:   "HTTP/1.0",
+				Headers: [][2]string{{"Accept", "*/*"}},
+			},
+		},
+		{
+			name:    "HTTP/2 export",
+			content: "GET / HTTP/2\r\nhost: example.com\r\n\r\n",
+			want: RawRequest{
+				Method:  "GET",
+				Path:    "/",
+				Proto:   "HTTP/1.1",
+				Headers: [][2]string{{"host", "example.com"}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			raw, err := parseRawRequest(tc.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if raw.Method != tc.want.Method || raw.Path != tc.want.Path || raw.Proto != tc.want.Proto || raw.Body != tc.want.Body {
+				t.Errorf("expected %+v, got %+v", tc.want, raw)
+			}
+			if !slices.Equal(raw.Headers, tc.want.Headers) {
+				t.Errorf("expected headers %v, got %v", tc.want.Headers, raw.Headers)
+			}
+		})
+	}
+
+	if _, err := parseRawRequest("GET /\r\n\r\n"); err == nil {
+		t.Error("expected an error for an invalid request line")
+	}
+}
+
+func TestRawRequestBuild(t *testing.T) {
+	raw, err := parseRawRequest("POST /search HTTP/2\r\nHost: old.example.com\r\nContent-Length: 1\r\nX-Target: {{host}}\r\n\r\nq={{url}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	built := string(raw.build(raw.Path, "new.example.com", "https://new.example.com/search"))
+	expected := strings.Join([]string{
+		"POST /search HTTP/1.1",
+		"Host: new.example.com",
+		"Content-Length: 32",
+		"X-Target: new.example.com",
+		"",
+		"q=https://new.example.com/search",
+	}, "\r\n")
+	if built != expected {
+		t.Errorf("expected\n%q\ngot\n%q", expected, built)
+	}
+}
@@ -0,0 +1,127 @@
+package probe
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// resolveOverrides pins hosts to addresses without touching /etc/hosts. The dialer connects to the
+// pinned address while requests keep the real hostname for the Host header and SNI.
+type resolveOverrides struct {
+	// addrs maps host:port to ip:port, from --resolve
+	addrs map[string]string
+	// resolved maps the hostnames of --resolve to their addresses, for lookups that have no port
+	resolved map[string][]net.IPAddr
+	// hosts maps hostnames to their addresses on every port, from --hosts-file
+	hosts map[string][]net.IPAddr
+}
+
+// parseResolveOverrides parses curl style host:port:ip entries and a hosts file ("ip hostname..." per line)
+func parseResolveOverrides(entries []string, hostsFile string) (*resolveOverrides, error) {
+	if len(entries) == 0 && hostsFile == "" {
+		return nil, nil
+	}
+
+	overrides := &resolveOverrides{
+		addrs:    make(map[string]string),
+		resolved: make(map[string][]net.IPAddr),
+		hosts:    make(map[string][]net.IPAddr),
+	}
+	for _, entry := range entries {
+		host, rest, found := strings.Cut(entry, ":")
+		port, ip, found2 := strings.Cut(rest, ":")
+		parsedIP := net.ParseIP(strings.Trim(ip, "[]"))
+		if !found || !found2 || host == "" || port == "" || parsedIP == nil {
+			return nil, fmt.Errorf("[!] invalid --resolve entry %q, expected host:port:ip", entry)
+		}
+		host = strings.ToLower(host)
+		overrides.addrs[net.JoinHostPort(host, port)] = net.JoinHostPort(parsedIP.String(), port)
+		if !slices.ContainsFunc(overrides.resolved[host], func(addr net.IPAddr) bool { return addr.IP.Equal(parsedIP) }) {
+			overrides.resolved[host] = append(overrides.resolved[host], net.IPAddr{IP: parsedIP})
+		}
+	}
+
+	if hostsFile != "" {
+		if err := overrides.readHostsFile(hostsFile); err != nil {
+			return nil, err
+		}
+	}
+	return overrides, nil
+}
+
+func (o *resolveOverrides) readHostsFile(filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("error reading hosts file %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		ip := net.ParseIP(fields[0])
+		if ip == nil {
+			return fmt.Errorf("[!] invalid address %s in hosts file %s", fields[0], filename)
+		}
+		for _, hostname := range fields[1:] {
+			hostname = strings.ToLower(hostname)
+			o.hosts[hostname] = append(o.hosts[hostname], net.IPAddr{IP: ip})
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error scanning hosts file %s: %w", filename, err)
+	}
+	return nil
+}
+
+// dialAddr returns the pinned address for a --resolve entry, or addr unchanged
+func (o *resolveOverrides) dialAddr(addr string) string {
+	if pinned, ok := o.addrs[strings.ToLower(addr)]; ok {
+		return pinned
+	}
+	return addr
+}
+
+// lookupAddr resolves the host of addr (host:port) like the dialer does: the --resolve entry for
+// addr first, then the prober's resolver, which applies the hosts file, scope and IP family
+func (p *Prober) lookupAddr(ctx context.Context, addr string) ([]net.IPAddr, error) {
+	if p.config.ResolveOverrides != nil {
+		addr = p.config.ResolveOverrides.dialAddr(addr)
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	return p.resolver.LookupIPAddr(ctx, host)
+}
+
+// overrideResolver answers with the --resolve addresses, then the hosts file ones, before asking the
+// real resolver. Lookups have no port, so a host pinned on several ports resolves to every pinned address.
+type overrideResolver struct {
+	resolver  fasthttp.Resolver
+	overrides *resolveOverrides
+}
+
+func (r *overrideResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	host = strings.ToLower(host)
+	// the tables are shared by every worker, so callers get copies they are free to modify
+	if addrs, ok := r.overrides.resolved[host]; ok {
+		return slices.Clone(addrs), nil
+	}
+	if addrs, ok := r.overrides.hosts[host]; ok {
+		return slices.Clone(addrs), nil
+	}
+	return r.resolver.LookupIPAddr(ctx, host)
+}
@@ -0,0 +1,132 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/GraveSIN/http-probe/internal/scope"
+)
+
+func TestResolveOverrides(t *testing.T) {
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	content := "# staging\n10.0.0.5 staging.example.com www.staging.example.com\n\n2001:db8::1 v6.example.com # comment\n"
+	if err := os.WriteFile(hostsFile, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	overrides, err := parseResolveOverrides([]string{"API.example.com:8443:10.0.0.7", "v6.example.com:443:[2001:db8::2]"}, hostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dialTests := map[string]string{
+		"api.example.com:8443": "10.0.0.7:8443",
+		"api.example.com:443":  "api.example.com:443",
+		"v6.example.com:443":   "[2001:db8::2]:443",
+	}
+	for addr, want := range dialTests {
+		if got := overrides.dialAddr(addr); got != want {
+			t.Errorf("dialAddr(%q) = %q, want %q", addr, got, want)
+		}
+	}
+
+	resolver := &overrideResolver{resolver: staticResolver{{IP: net.ParseIP("192.0.2.1")}}, overrides: overrides}
+	// --resolve entries come first, like they do when dialing
+	lookupTests := map[string]string{
+		"WWW.staging.example.com": "10.0.0.5",
+		"api.example.com":         "10.0.0.7",
+		"v6.example.com":          "2001:db8::2",
+		"other.example.com":       "192.0.2.1",
+	}
+	for host, want := range lookupTests {
+		addrs, err := resolver.LookupIPAddr(context.Background(), host)
+		if err != nil || len(addrs) != 1 || addrs[0].IP.String() != want {
+			t.Errorf("LookupIPAddr(%q) = %v, %v, want %s", host, addrs, err, want)
+		}
+	}
+}
+
+func TestResolveOverridesWithScope(t *testing.T) {
+	overrides, err := parseResolveOverrides([]string{"api.example.com:443:10.0.0.7", "api.example.com:8443:192.0.2.7"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiScope, err := scope.New(nil, []string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver := newResolver(&ProberConfig{ResolveOverrides: overrides, Scope: apiScope})
+
+	// workers look the host up concurrently, and filtering out the denied address must not touch the table
+	var waitGroup sync.WaitGroup
+	for range 8 {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			addrs, err := resolver.LookupIPAddr(context.Background(), "api.example.com")
+			if err != nil || len(addrs) != 1 || addrs[0].IP.String() != "192.0.2.7" {
+				t.Errorf("expected only the in-scope address, got %v, %v", addrs, err)
+			}
+		}()
+	}
+	waitGroup.Wait()
+
+	if addrs := overrides.resolved["api.example.com"]; len(addrs) != 2 || addrs[0].IP.String() != "10.0.0.7" || addrs[1].IP.String() != "192.0.2.7" {
+		t.Errorf("expected the --resolve table to be left untouched, got %v", addrs)
+	}
+}
+
+func TestParseResolveOverridesInvalid(t *testing.T) {
+	for _, entry := range []string{"example.com", "example.com:443", "example.com:443:not-an-ip", ":443:10.0.0.1"} {
+		if _, err := parseResolveOverrides([]string{entry}, ""); err == nil {
+			t.Errorf("parseResolveOverrides(%q) returned no error", entry)
+		}
+	}
+	if overrides, err := parseResolveOverrides(nil, ""); overrides != nil || err != nil {
+		t.Errorf("parseResolveOverrides(nil) = %v, %v, want nil", overrides, err)
+	}
+}
+
+func TestResolveOverridesRouting(t *testing.T) {
+	listener, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("::1 is not available: %v", err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html><title>Pinned</title></html>")
+	}))
+	server.Listener = listener
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.Start()
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	overrides, err := parseResolveOverrides([]string{"pinned.invalid:" + port + ":[::1]"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := "http://pinned.invalid:" + port
+
+	// the pinned IPv6 address is dialed without -6 or --dual-stack
+	prober := NewProber(&ProberConfig{URLs: &[]string{}, Threads: 1, Timeout: 5, Method: "GET", ResolveOverrides: overrides})
+	if results := prober.Probe(url); len(results) != 1 || results[0].Title != "Pinned" {
+		t.Errorf("expected the pinned IPv6 address to answer, got %+v", results)
+	}
+	if timings, err := prober.Timings(url); err != nil || !timings.IP.Equal(net.ParseIP("::1")) {
+		t.Errorf("expected timings against ::1, got %+v, %v", timings, err)
+	}
+
+	prober = NewProber(&ProberConfig{URLs: &[]string{}, Threads: 1, Timeout: 5, Method: "GET", ResolveOverrides: overrides, AllIPs: true})
+	if results := prober.Probe(url); len(results) != 1 || results[0].DialAddress != "::1" || results[0].Title != "Pinned" {
+		t.Errorf("expected --all-ips to probe the pinned address, got %+v", results)
+	}
+}
@@ -0,0 +1,125 @@
+package probe
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/GraveSIN/http-probe/internal/utils"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	// number of random paths requested to build a host's baseline
+	soft404Samples = 3
+	// maximum simhash distance for a body to be considered the same page
+	soft404MaxDistance = 6
+	// maximum relative content length difference when simhashes disagree
+	soft404LengthTolerance = 0.02
+)
+
+// responseFingerprint summarizes a response for soft-404 comparisons
+type responseFingerprint struct {
+	StatusCode    int
+	ContentLength int
+	Simhash       uint64
+	Title         string
+}
+
+// buildSoft404Baseline requests a few random paths that should not exist on the host, with the
+// credentials of the probe, and fingerprints the responses. Hosts that answer them with 404 yield no baseline.
+func (p *Prober) buildSoft404Baseline(scheme, host, pinned string, credentials *Credentials) []responseFingerprint {
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	var fingerprints []responseFingerprint
+	for range soft404Samples {
+		req.Reset()
+		resp.Reset()
+
+		req.SetRequestURI(scheme + "://" + host + "/" + randomToken(8))
+		req.Header.SetMethod(fasthttp.MethodGet)
+		for key, value := range defaultHeaders {
+			req.Header.Set(key, value)
+		}
+
+		if err := p.doAuthenticatedRequest(pinned, credentials, req, resp); err != nil {
+			continue
+		}
+		if resp.StatusCode() == fasthttp.StatusNotFound {
+			continue
+		}
+		fingerprints = append(fingerprints, fingerprintResponse(resp))
+	}
+
+	return fingerprints
+}
+
+// fingerprintResponse extracts the fields used to compare a response against a baseline
+func fingerprintResponse(resp *fasthttp.Response) responseFingerprint {
+	body, err := resp.BodyUncompressed()
+	if err != nil {
+		body = resp.Body()
+	}
+
+	return responseFingerprint{
+		StatusCode:    resp.StatusCode(),
+		ContentLength: len(body),
+		Simhash:       utils.Simhash(body),
+		Title:         utils.GetHTTPTitleFromBody(body),
+	}
+}
+
+// matches reports whether a response fingerprint is indistinguishable from a baseline sample
+func (f responseFingerprint) matches(other responseFingerprint) bool {
+	if f.StatusCode != other.StatusCode || f.Title != other.Title {
+		return false
+	}
+
+	if utils.HammingDistance(f.Simhash, other.Simhash) <= soft404MaxDistance {
+		return true
+	}
+
+	larger := max(f.ContentLength, other.ContentLength)
+	if larger == 0 {
+		return true
+	}
+	diff := f.ContentLength - other.ContentLength
+	if diff < 0 {
+		diff = -diff
+	}
+	return float64(diff)/float64(larger) <= soft404LengthTolerance
+}
+
+// isLikelySoft404 compares the response with the baseline of the host and address it was served from
+func (p *Prober) isLikelySoft404(pinned string, credentials *Credentials, req *fasthttp.Request, resp *fasthttp.Response) bool {
+	scheme, host := string(req.URI().Scheme()), string(req.URI().Host())
+	// baselines are shared across workers, keyed by scheme://host, the pinned address and whether
+	// they were built behind the credentials
+	key := scheme + "://" + host + "|" + pinned
+	if credentials != nil {
+		key += "|auth"
+	}
+	baseline := p.soft404.get(key, func() []responseFingerprint {
+		return p.buildSoft404Baseline(scheme, host, pinned, credentials)
+	})
+	if len(baseline) == 0 {
+		return false
+	}
+
+	fingerprint := fingerprintResponse(resp)
+	for _, sample := range baseline {
+		if fingerprint.matches(sample) {
+			return true
+		}
+	}
+	return false
+}
+
+// randomToken returns a random hex string of n bytes
+func randomToken(n int) string {
+	buf := make([]byte, n)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
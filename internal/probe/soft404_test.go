@@ -0,0 +1,46 @@
+package probe
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsLikelySoft404(t *testing.T) {
+	catchAll := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html><title>Welcome</title><body>Sorry, we couldn't find that page.</body></html>")
+	}))
+	defer catchAll.Close()
+
+	strict := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/admin" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "<html><title>Admin</title><body>Dashboard</body></html>")
+	}))
+	defer strict.Close()
+
+	tests := []struct {
+		name     string
+		url      string
+		expected bool
+	}{
+		{"catch-all answers every path alike", catchAll.URL + "/admin", true},
+		{"real page on a host returning 404", strict.URL + "/admin", false},
+	}
+
+	prober := NewProber(&ProberConfig{URLs: &[]string{}, Threads: 1, Timeout: 5, Method: "GET", Soft404: true})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := prober.Probe(tt.url)
+			if len(results) != 1 || results[0].StatusCode != http.StatusOK {
+				t.Fatalf("expected a 200 result, got %+v", results)
+			}
+			if results[0].LikelySoft404 != tt.expected {
+				t.Errorf("expected LikelySoft404 %v, got %v", tt.expected, results[0].LikelySoft404)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package probe
+
+import (
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/valyala/fasthttp"
+)
+
+// probeTarget is a single unit of work for the prober
+type probeTarget struct {
+	URL string
+	// BaseURL and Path are set when the URL was built from a path list
+	BaseURL string
+	Path    string
+	// Depth and DiscoveredFrom are set on targets found by --discover
+	Depth          int
+	DiscoveredFrom string
+	// DialAddr, when set, is connected to instead of the address the URL's host resolves to
+	DialAddr string
+}
+
+// newPathTarget joins a path onto the path of a base URL. The query and fragment of the base URL
+// belong to the page it points to, so they are dropped; a query in the path is kept.
+func newPathTarget(baseURL, path string) probeTarget {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	base := baseURL
+	if parsed, err := url.Parse(baseURL); err == nil {
+		parsed.RawQuery, parsed.ForceQuery = "", false
+		parsed.Fragment, parsed.RawFragment = "", ""
+		base = parsed.String()
+	}
+	return probeTarget{
+		URL:     strings.TrimSuffix(base, "/") + path,
+		BaseURL: baseURL,
+		Path:    path,
+	}
+}
+
+// hostnameOf returns the host of the URI without its port
+func hostnameOf(uri *fasthttp.URI) string {
+	hostname, _, err := net.SplitHostPort(fasthttp.AddMissingPort(string(uri.Host()), string(uri.Scheme()) == "https"))
+	if err != nil {
+		return string(uri.Host())
+	}
+	return hostname
+}
+
+// hostCache computes a value once per key and shares it across workers
+type hostCache[T any] struct {
+	entries sync.Map
+}
+
+type hostCacheEntry[T any] struct {
+	once  sync.Once
+	value T
+}
+
+// get returns the cached value for key, calling compute on first use
+func (c *hostCache[T]) get(key string, compute func() T) T {
+	value, _ := c.entries.LoadOrStore(key, &hostCacheEntry[T]{})
+	entry := value.(*hostCacheEntry[T])
+	entry.once.Do(func() {
+		entry.value = compute()
+	})
+	return entry.value
+}
@@ -0,0 +1,68 @@
+package probe
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+
+	"github.com/GraveSIN/http-probe/internal/targets"
+)
+
+func TestNewPathTarget(t *testing.T) {
+	tests := []struct {
+		baseURL  string
+		path     string
+		expected string
+	}{
+		{"https://example.com", "admin", "https://example.com/admin"},
+		{"https://example.com/", "/admin", "https://example.com/admin"},
+		{"https://example.com/app/", "admin", "https://example.com/app/admin"},
+		{"https://example.com/app", "admin", "https://example.com/app/admin"},
+		{"https://example.com/?x=1", "admin", "https://example.com/admin"},
+		{"https://example.com/app?x=1#top", "admin", "https://example.com/app/admin"},
+		{"https://example.com/?", "admin", "https://example.com/admin"},
+		{"https://example.com:8443/", "admin?debug=1", "https://example.com:8443/admin?debug=1"},
+		{"https://[2001:db8::1]:8443", "admin", "https://[2001:db8::1]:8443/admin"},
+	}
+
+	for _, test := range tests {
+		target := newPathTarget(test.baseURL, test.path)
+		if target.URL != test.expected {
+			t.Errorf("%s + %s: expected %s, got %s", test.baseURL, test.path, test.expected, target.URL)
+		}
+		if target.BaseURL != test.baseURL {
+			t.Errorf("%s + %s: expected the base URL to be kept, got %s", test.baseURL, test.path, target.BaseURL)
+		}
+	}
+}
+
+func TestInitializeWorkPoolPaths(t *testing.T) {
+	prober := NewProber(&ProberConfig{
+		URLs:    &[]string{"https://example.com/?x=1"},
+		Paths:   []string{"admin", "/login"},
+		Threads: 1,
+		Timeout: 1,
+		Expansion: &targets.Expansion{Ranges: []targets.Range{{
+			First: netip.MustParseAddr("192.0.2.1"),
+			Last:  netip.MustParseAddr("192.0.2.2"),
+		}}},
+	})
+	go prober.initializeWorkPool()
+
+	var urls []string
+	for target := range prober.workPool {
+		urls = append(urls, target.URL)
+	}
+	// paths are the outer loop, and the expanded addresses follow the input URLs
+	expected := []string{
+		"https://example.com/admin",
+		"https://192.0.2.1/admin",
+		"https://192.0.2.2/admin",
+		"https://example.com/login",
+		"https://192.0.2.1/login",
+		"https://192.0.2.2/login",
+	}
+	if !slices.Equal(urls, expected) {
+		t.Errorf("expected %v, got %v", expected, urls)
+	}
+}
@@ -0,0 +1,219 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// TLSScanResult is the TLS configuration accepted by a server
+type TLSScanResult struct {
+	Versions []string `json:"versions"`
+	// CipherSuites lists the accepted suites per protocol version. TLS 1.3 suites can't be
+	// restricted by the client, so only the one the server picked is reported for it.
+	CipherSuites           map[string][]string `json:"cipher_suites"`
+	ServerCipherPreference bool                `json:"server_cipher_preference"`
+	Curves                 []string            `json:"curves,omitempty"`
+	OCSPStapling           bool                `json:"ocsp_stapling"`
+	SessionResumption      bool                `json:"session_resumption"`
+	Grade                  string              `json:"grade"`
+	Issues                 []string            `json:"issues,omitempty"`
+}
+
+// scannedVersions are tried from oldest to newest
+var scannedVersions = []uint16{tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13}
+
+var scannedCurves = []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384, tls.CurveP521}
+
+// tlsScanSuites are every suite crypto/tls can offer for TLS 1.0 to 1.2, secure ones first.
+// Suites Go doesn't implement (DHE, CAMELLIA, ARIA, PSK, export, ...) are never offered, so a
+// server accepting only those is reported as not supporting the version.
+var tlsScanSuites = append(tls.CipherSuites(), tls.InsecureCipherSuites()...)
+
+// scanTLS enumerates the protocol versions, cipher suites and curves accepted by the host, cached per host
+// and pinned address
+func (p *Prober) scanTLS(host, pinned string) *TLSScanResult {
+	return p.tlsScans.get(host+"|"+pinned, func() *TLSScanResult {
+		return p.runTLSScan(host, pinned)
+	})
+}
+
+func (p *Prober) runTLSScan(host, pinned string) *TLSScanResult {
+	result := &TLSScanResult{CipherSuites: make(map[string][]string)}
+
+	for _, version := range scannedVersions {
+		versionName := tls.VersionName(version)
+
+		if version == tls.VersionTLS13 {
+			if state, ok := p.tlsHandshake(host, pinned, func(config *tls.Config) {
+				config.MinVersion, config.MaxVersion = version, version
+			}); ok {
+				result.Versions = append(result.Versions, versionName)
+				result.CipherSuites[versionName] = []string{tls.CipherSuiteName(state.CipherSuite)}
+			}
+			continue
+		}
+
+		var accepted []uint16
+		for _, suite := range tlsScanSuites {
+			if !slices.Contains(suite.SupportedVersions, version) {
+				continue
+			}
+			if _, ok := p.tlsHandshake(host, pinned, func(config *tls.Config) {
+				config.MinVersion, config.MaxVersion = version, version
+				config.CipherSuites = []uint16{suite.ID}
+			}); ok {
+				accepted = append(accepted, suite.ID)
+				result.CipherSuites[versionName] = append(result.CipherSuites[versionName], suite.Name)
+			}
+		}
+
+		if len(accepted) == 0 {
+			continue
+		}
+		result.Versions = append(result.Versions, versionName)
+
+		// the server enforces its own order when it picks the same suite whatever the client prefers
+		if len(accepted) > 1 && version == tls.VersionTLS12 {
+			result.ServerCipherPreference = p.prefersServerCiphers(host, pinned, version, accepted[0], accepted[len(accepted)-1])
+		}
+	}
+
+	if len(result.Versions) == 0 {
+		return result
+	}
+
+	for _, curve := range scannedCurves {
+		if _, ok := p.tlsHandshake(host, pinned, func(config *tls.Config) {
+			config.CurvePreferences = []tls.CurveID{curve}
+		}); ok {
+			result.Curves = append(result.Curves, curve.String())
+		}
+	}
+
+	if state, ok := p.tlsHandshake(host, pinned, func(config *tls.Config) {}); ok {
+		result.OCSPStapling = len(state.OCSPResponse) > 0
+	}
+	result.SessionResumption = p.resumesSessions(host, pinned)
+
+	gradeTLSScan(result)
+	return result
+}
+
+// tlsHandshake performs a single handshake with a copy of the client's TLS config adjusted by configure,
+// with the pinned address when there is one
+func (p *Prober) tlsHandshake(host, pinned string, configure func(config *tls.Config)) (tls.ConnectionState, bool) {
+	addr := fasthttp.AddMissingPort(host, true)
+	tlsConfig := p.client.TLSConfig.Clone()
+	tlsConfig.ServerName, _, _ = net.SplitHostPort(addr)
+	configure(tlsConfig)
+
+	return p.handshakeWithConfig(pinnedAddress(pinned, addr), tlsConfig)
+}
+
+func (p *Prober) handshakeWithConfig(addr string, tlsConfig *tls.Config) (tls.ConnectionState, bool) {
+	conn, err := p.client.Dial(addr)
+	if err != nil {
+		return tls.ConnectionState{}, false
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout())
+	defer cancel()
+
+	tlsConn := tls.Client(conn, tlsConfig)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return tls.ConnectionState{}, false
+	}
+	return tlsConn.ConnectionState(), true
+}
+
+// prefersServerCiphers offers two accepted suites in both orders and checks whether the server's pick changes
+func (p *Prober) prefersServerCiphers(host, pinned string, version, first, second uint16) bool {
+	negotiate := func(suites ...uint16) uint16 {
+		state, _ := p.tlsHandshake(host, pinned, func(config *tls.Config) {
+			config.MinVersion, config.MaxVersion = version, version
+			config.CipherSuites = suites
+		})
+		return state.CipherSuite
+	}
+
+	return negotiate(first, second) == negotiate(second, first)
+}
+
+// resumesSessions reconnects with the session ticket or ID from a first handshake
+func (p *Prober) resumesSessions(host, pinned string) bool {
+	addr := fasthttp.AddMissingPort(host, true)
+	tlsConfig := p.client.TLSConfig.Clone()
+	tlsConfig.ServerName, _, _ = net.SplitHostPort(addr)
+	tlsConfig.ClientSessionCache = tls.NewLRUClientSessionCache(1)
+	addr = pinnedAddress(pinned, addr)
+
+	// TLS 1.3 tickets arrive after the handshake, so read briefly to receive them
+	conn, err := p.client.Dial(addr)
+	if err != nil {
+		return false
+	}
+	tlsConn := tls.Client(conn, tlsConfig)
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout())
+	defer cancel()
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return false
+	}
+	tlsConn.Write([]byte("HEAD / HTTP/1.1\r\nHost: " + tlsConfig.ServerName + "\r\nConnection: close\r\n\r\n"))
+	tlsConn.Read(make([]byte, 1))
+	tlsConn.Close()
+
+	state, ok := p.handshakeWithConfig(addr, tlsConfig)
+	return ok && state.DidResume
+}
+
+// gradeTLSScan grades the result from A to F and lists the issues behind the grade
+func gradeTLSScan(result *TLSScanResult) {
+	grade := "A"
+	downgrade := func(to, issue string) {
+		result.Issues = append(result.Issues, issue)
+		if to > grade {
+			grade = to
+		}
+	}
+
+	for _, version := range []string{"TLS 1.0", "TLS 1.1"} {
+		if slices.Contains(result.Versions, version) {
+			downgrade("B", version+" accepted")
+		}
+	}
+	if !slices.Contains(result.Versions, "TLS 1.2") && !slices.Contains(result.Versions, "TLS 1.3") {
+		downgrade("F", "neither TLS 1.2 nor TLS 1.3 accepted")
+	}
+	if !slices.Contains(result.Versions, "TLS 1.3") {
+		result.Issues = append(result.Issues, "TLS 1.3 not supported")
+	}
+
+	forwardSecrecy := slices.Contains(result.Versions, "TLS 1.3")
+	for _, suites := range result.CipherSuites {
+		for _, suite := range suites {
+			switch {
+			case strings.Contains(suite, "RC4"):
+				downgrade("F", suite+" (RC4) accepted")
+			case strings.Contains(suite, "3DES"):
+				downgrade("C", suite+" (3DES) accepted")
+			case strings.Contains(suite, "_CBC_"):
+				downgrade("B", suite+" (CBC, Lucky13) accepted")
+			}
+			if strings.Contains(suite, "ECDHE") {
+				forwardSecrecy = true
+			}
+		}
+	}
+	if !forwardSecrecy {
+		downgrade("B", "no forward secrecy")
+	}
+
+	result.Grade = grade
+}
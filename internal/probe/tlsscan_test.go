@@ -0,0 +1,87 @@
+package probe
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestGradeTLSScan(t *testing.T) {
+	testCases := []struct {
+		name     string
+		result   TLSScanResult
+		expected string
+	}{
+		{
+			name: "modern",
+			result: TLSScanResult{
+				Versions: []string{"TLS 1.2", "TLS 1.3"},
+				CipherSuites: map[string][]string{
+					"TLS 1.2": {"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+					"TLS 1.3": {"TLS_AES_128_GCM_SHA256"},
+				},
+			},
+			expected: "A",
+		},
+		{
+			name: "legacy versions",
+			result: TLSScanResult{
+				Versions:     []string{"TLS 1.0", "TLS 1.2"},
+				CipherSuites: map[string][]string{"TLS 1.0": {"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA"}, "TLS 1.2": {"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}},
+			},
+			expected: "B",
+		},
+		{
+			name: "3DES",
+			result: TLSScanResult{
+				Versions:     []string{"TLS 1.2"},
+				CipherSuites: map[string][]string{"TLS 1.2": {"TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA"}},
+			},
+			expected: "C",
+		},
+		{
+			name: "CBC with HMAC-SHA1",
+			result: TLSScanResult{
+				Versions: []string{"TLS 1.2", "TLS 1.3"},
+				CipherSuites: map[string][]string{
+					"TLS 1.2": {"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA"},
+					"TLS 1.3": {"TLS_AES_128_GCM_SHA256"},
+				},
+			},
+			expected: "B",
+		},
+		{
+			name: "CBC with HMAC-SHA256",
+			result: TLSScanResult{
+				Versions: []string{"TLS 1.2", "TLS 1.3"},
+				CipherSuites: map[string][]string{
+					"TLS 1.2": {"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256"},
+					"TLS 1.3": {"TLS_AES_128_GCM_SHA256"},
+				},
+			},
+			expected: "B",
+		},
+		{
+			name: "RC4",
+			result: TLSScanResult{
+				Versions:     []string{"TLS 1.2"},
+				CipherSuites: map[string][]string{"TLS 1.2": {"TLS_RSA_WITH_RC4_128_SHA"}},
+			},
+			expected: "F",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gradeTLSScan(&tc.result)
+			if tc.result.Grade != tc.expected {
+				t.Errorf("expected %s, got %s (%v)", tc.expected, tc.result.Grade, tc.result.Issues)
+			}
+		})
+	}
+
+	result := TLSScanResult{Versions: []string{"TLS 1.2"}, CipherSuites: map[string][]string{"TLS 1.2": {"TLS_RSA_WITH_AES_128_GCM_SHA256"}}}
+	gradeTLSScan(&result)
+	if !slices.Contains(result.Issues, "no forward secrecy") {
+		t.Errorf("expected no forward secrecy issue, got %v", result.Issues)
+	}
+}
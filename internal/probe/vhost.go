@@ -0,0 +1,89 @@
+package probe
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// number of random hostnames requested to build the baseline of an address
+const vhostBaselineSamples = 2
+
+// newVHostTarget builds the target requesting hostname from the address of baseURL
+func newVHostTarget(baseURL, hostname string) (probeTarget, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return probeTarget{}, err
+	}
+
+	// the address is kept without brackets, and with the port only when the URL has one
+	host, dialAddr := hostname, parsed.Hostname()
+	if port := parsed.Port(); port != "" {
+		host += ":" + port
+		dialAddr = net.JoinHostPort(dialAddr, port)
+	}
+	return probeTarget{
+		URL:      parsed.Scheme + "://" + host + "/",
+		BaseURL:  baseURL,
+		DialAddr: dialAddr,
+	}, nil
+}
+
+// isDefaultVHost reports whether the response for a wordlist hostname is indistinguishable from the
+// one served for random hostnames under the same parent domain, i.e. the address's default site
+func (p *Prober) isDefaultVHost(target probeTarget, req *fasthttp.Request, resp *fasthttp.Response) bool {
+	uri := req.URI()
+	scheme := string(uri.Scheme())
+	_, parent, _ := strings.Cut(hostnameOf(uri), ".")
+	_, port, _ := net.SplitHostPort(string(uri.Host()))
+
+	// baselines are shared across workers, keyed by address, scheme and parent domain
+	baseline := p.vhostBaselines.get(target.DialAddr+"|"+scheme+"|"+parent, func() []responseFingerprint {
+		return p.buildVHostBaseline(target.DialAddr, scheme, parent, port)
+	})
+
+	fingerprint := fingerprintResponse(resp)
+	for _, sample := range baseline {
+		if fingerprint.matches(sample) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildVHostBaseline requests random hostnames under parent from the pinned address. Addresses that
+// reject unknown hostnames yield no baseline, so every hostname they answer is distinct.
+func (p *Prober) buildVHostBaseline(pinned, scheme, parent, port string) []responseFingerprint {
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	var fingerprints []responseFingerprint
+	for range vhostBaselineSamples {
+		req.Reset()
+		resp.Reset()
+
+		hostname := randomToken(6)
+		if parent != "" {
+			hostname += "." + parent
+		}
+		if port != "" {
+			hostname += ":" + port
+		}
+		req.SetRequestURI(scheme + "://" + hostname + "/")
+		req.Header.SetMethod(fasthttp.MethodGet)
+		for key, value := range defaultHeaders {
+			req.Header.Set(key, value)
+		}
+
+		if err := p.doPinnedRequest(pinned, req, resp); err != nil {
+			continue
+		}
+		fingerprints = append(fingerprints, fingerprintResponse(resp))
+	}
+
+	return fingerprints
+}
@@ -0,0 +1,111 @@
+package probe
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestVHostDiscovery(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hostname, _, _ := strings.Cut(r.Host, ":")
+		if hostname == "intranet.example.test" && r.TLS.ServerName == hostname {
+			fmt.Fprint(w, "<html><title>Intranet</title><body>internal wiki</body></html>")
+			return
+		}
+		fmt.Fprint(w, "<html><title>Default</title><body>It works!</body></html>")
+	}))
+	defer server.Close()
+
+	vhosts := []string{"www.example.test", "intranet.example.test"}
+	prober := NewProber(&ProberConfig{URLs: &[]string{}, Threads: 1, Timeout: 5, Method: "GET", VHosts: vhosts})
+
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	var found []string
+	for _, hostname := range vhosts {
+		target, err := newVHostTarget(server.URL, hostname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result := prober.probeURL(target, req, resp); result.StatusLine != "" {
+			found = append(found, result.Title)
+			if result.DialAddress != strings.TrimPrefix(server.URL, "https://") {
+				t.Errorf("expected dial address %s, got %s", server.URL, result.DialAddress)
+			}
+		}
+	}
+
+	if len(found) != 1 || found[0] != "Intranet" {
+		t.Errorf("expected only the intranet virtual host, got %v", found)
+	}
+}
+
+func TestNewVHostTarget(t *testing.T) {
+	testCases := []struct {
+		baseURL  string
+		url      string
+		dialAddr string
+		// dialed is the address connected to for the request's host:port
+		dialed string
+	}{
+		{"https://192.0.2.1", "https://intranet.example.test/", "192.0.2.1", "192.0.2.1:443"},
+		{"http://192.0.2.1:8080/", "http://intranet.example.test:8080/", "192.0.2.1:8080", "192.0.2.1:8080"},
+		{"https://[2001:db8::1]", "https://intranet.example.test/", "2001:db8::1", "[2001:db8::1]:443"},
+		{"https://[2001:db8::1]:8443", "https://intranet.example.test:8443/", "[2001:db8::1]:8443", "[2001:db8::1]:8443"},
+	}
+
+	for _, tc := range testCases {
+		target, err := newVHostTarget(tc.baseURL, "intranet.example.test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if target.URL != tc.url || target.DialAddr != tc.dialAddr {
+			t.Errorf("%s: expected %s dialing %s, got %s dialing %s", tc.baseURL, tc.url, tc.dialAddr, target.URL, target.DialAddr)
+		}
+		uri := fasthttp.AcquireURI()
+		uri.Parse(nil, []byte(target.URL))
+		addr := fasthttp.AddMissingPort(string(uri.Host()), string(uri.Scheme()) == "https")
+		fasthttp.ReleaseURI(uri)
+		if dialed := pinnedAddress(target.DialAddr, addr); dialed != tc.dialed {
+			t.Errorf("%s: expected to dial %s, got %s", tc.baseURL, tc.dialed, dialed)
+		}
+	}
+}
+
+func TestVHostDiscoveryIPv6(t *testing.T) {
+	listener, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("::1 is not available: %v", err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "<html><title>%s</title></html>", r.Host)
+	}))
+	server.Listener = listener
+	server.StartTLS()
+	defer server.Close()
+
+	prober := NewProber(&ProberConfig{URLs: &[]string{}, Threads: 1, Timeout: 5, Method: "GET"})
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	target, err := newVHostTarget(server.URL, "intranet.example.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	result := prober.probeURL(target, req, resp)
+	if result.Title != "intranet.example.test:"+port {
+		t.Errorf("expected the virtual host to be served from [::1], got %+v", result)
+	}
+}
@@ -0,0 +1,239 @@
+package scope
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Scope decides which hosts may be probed from the rules of an engagement.
+// Hosts matching an out-of-scope rule are always blocked; when in-scope rules are given,
+// a host must match one of them.
+type Scope struct {
+	allow []rule
+	deny  []rule
+}
+
+// rule is a single line of a scope file: a CIDR range or IP address, a /regex/, or a hostname
+// where * and ? are wildcards (*.example.com)
+type rule struct {
+	pattern string
+	network *net.IPNet
+	regexp  *regexp.Regexp
+}
+
+// BlockedError reports a host that is out of scope and why
+type BlockedError struct {
+	Host   string
+	Reason string
+}
+
+func (e *BlockedError) Error() string {
+	return e.Host + " is out of scope: " + e.Reason
+}
+
+// Load reads the in-scope and out-of-scope files, either of which may be empty.
+// It returns nil when neither is given.
+func Load(scopeFile, outOfScopeFile string) (*Scope, error) {
+	if scopeFile == "" && outOfScopeFile == "" {
+		return nil, nil
+	}
+
+	s := &Scope{}
+	var err error
+	if scopeFile != "" {
+		if s.allow, err = readRules(scopeFile); err != nil {
+			return nil, err
+		}
+	}
+	if outOfScopeFile != "" {
+		if s.deny, err = readRules(outOfScopeFile); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// New builds a scope from in-scope and out-of-scope rules
+func New(allow, deny []string) (*Scope, error) {
+	s := &Scope{}
+	for _, line := range allow {
+		r, err := parseRule(line)
+		if err != nil {
+			return nil, err
+		}
+		s.allow = append(s.allow, r)
+	}
+	for _, line := range deny {
+		r, err := parseRule(line)
+		if err != nil {
+			return nil, err
+		}
+		s.deny = append(s.deny, r)
+	}
+	return s, nil
+}
+
+func readRules(filename string) ([]rule, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading scope file: %w", err)
+	}
+	defer file.Close()
+
+	var rules []rule
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r, err := parseRule(line)
+		if err != nil {
+			return nil, fmt.Errorf("[!] %s:%d: %w", filename, lineNumber, err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, scanner.Err()
+}
+
+func parseRule(line string) (rule, error) {
+	r := rule{pattern: line}
+
+	switch {
+	case len(line) > 2 && strings.HasPrefix(line, "/") && strings.HasSuffix(line, "/"):
+		re, err := regexp.Compile("(?i)" + line[1:len(line)-1])
+		if err != nil {
+			return r, fmt.Errorf("invalid regex %s: %w", line, err)
+		}
+		r.regexp = re
+	case strings.Contains(line, "/"):
+		_, network, err := net.ParseCIDR(line)
+		if err != nil {
+			return r, fmt.Errorf("invalid CIDR range %s: %w", line, err)
+		}
+		r.network = network
+	default:
+		if ip := net.ParseIP(line); ip != nil {
+			r.network = &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)}
+			if ip4 := ip.To4(); ip4 != nil {
+				r.network = &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}
+			}
+			break
+		}
+		r.pattern = strings.ToLower(strings.TrimSuffix(line, "."))
+		if _, err := path.Match(r.pattern, ""); err != nil {
+			return r, fmt.Errorf("invalid wildcard %s: %w", line, err)
+		}
+	}
+
+	return r, nil
+}
+
+// matchesHost reports whether a hostname or regex rule matches hostname
+func (r rule) matchesHost(hostname string) bool {
+	switch {
+	case r.network != nil:
+		return false
+	case r.regexp != nil:
+		return r.regexp.MatchString(hostname)
+	default:
+		matched, _ := path.Match(r.pattern, hostname)
+		return matched
+	}
+}
+
+func (r rule) matchesIP(ip net.IP) bool {
+	return r.network != nil && r.network.Contains(ip)
+}
+
+// CheckHost is checked on input, before any connection. Hostnames only allowed by CIDR ranges
+// pass and are checked again once resolved, by CheckAddress.
+func (s *Scope) CheckHost(host string) error {
+	hostname := normalize(host)
+	if ip := net.ParseIP(hostname); ip != nil {
+		return s.CheckAddress(hostname, ip)
+	}
+
+	for _, r := range s.deny {
+		if r.matchesHost(hostname) {
+			return &BlockedError{Host: hostname, Reason: "matches out-of-scope rule " + r.pattern}
+		}
+	}
+	if len(s.allow) == 0 {
+		return nil
+	}
+	for _, r := range s.allow {
+		if r.matchesHost(hostname) || r.network != nil {
+			return nil
+		}
+	}
+	return &BlockedError{Host: hostname, Reason: "matches no in-scope rule"}
+}
+
+// CheckAddress is checked at dial time for every address the host resolves to
+func (s *Scope) CheckAddress(host string, ip net.IP) error {
+	hostname := normalize(host)
+
+	for _, r := range s.deny {
+		if r.matchesIP(ip) || r.matchesHost(hostname) {
+			return &BlockedError{Host: hostname, Reason: fmt.Sprintf("%s matches out-of-scope rule %s", ip, r.pattern)}
+		}
+	}
+	if len(s.allow) == 0 {
+		return nil
+	}
+	for _, r := range s.allow {
+		if r.matchesIP(ip) || r.matchesHost(hostname) {
+			return nil
+		}
+	}
+	return &BlockedError{Host: hostname, Reason: fmt.Sprintf("%s matches no in-scope rule", ip)}
+}
+
+func normalize(host string) string {
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	return strings.ToLower(strings.TrimSuffix(strings.Trim(host, "[]"), "."))
+}
+
+// IPResolver resolves hostnames to addresses, like net.Resolver and fasthttp.Resolver
+type IPResolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// Resolver wraps an IPResolver and drops the resolved addresses that are out of scope,
+// so a hostname resolving into a blocked range can't be dialed
+type Resolver struct {
+	Resolver IPResolver
+	Scope    *Scope
+}
+
+// LookupIPAddr returns the in-scope addresses of host, or a *BlockedError when none is left
+func (r *Resolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	addrs, err := r.Resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	// the inner resolver may return a slice it keeps, such as the --resolve table, so filter into a new one
+	allowed := make([]net.IPAddr, 0, len(addrs))
+	var blocked error
+	for _, addr := range addrs {
+		if err := r.Scope.CheckAddress(host, addr.IP); err != nil {
+			blocked = err
+			continue
+		}
+		allowed = append(allowed, addr)
+	}
+	if len(allowed) == 0 && blocked != nil {
+		return nil, blocked
+	}
+	return allowed, nil
+}
@@ -0,0 +1,83 @@
+package scope
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestCheckHost(t *testing.T) {
+	s, err := New(
+		[]string{"*.example.com", "/^api[0-9]+\\.example\\.org$/", "10.0.0.0/8"},
+		[]string{"admin.example.com", "10.0.5.0/24"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		host    string
+		blocked bool
+	}{
+		{"www.example.com", false},
+		{"WWW.Example.com.", false},
+		{"www.example.com:8443", false},
+		{"admin.example.com", true},
+		{"api1.example.org", false},
+		{"www.example.org", false}, // only allowed by a CIDR range, checked once resolved
+		{"10.1.2.3", false},
+		{"10.0.5.1", true},
+		{"192.168.1.1", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.host, func(t *testing.T) {
+			err := s.CheckHost(tc.host)
+			if blocked := err != nil; blocked != tc.blocked {
+				t.Errorf("expected blocked %t, got %v", tc.blocked, err)
+			}
+		})
+	}
+
+	if err := s.CheckAddress("www.example.org", net.ParseIP("10.2.0.1")); err != nil {
+		t.Errorf("expected an address in an in-scope range to be allowed, got %v", err)
+	}
+	if err := s.CheckAddress("www.example.org", net.ParseIP("192.168.1.1")); err == nil {
+		t.Error("expected an address outside the in-scope ranges to be blocked")
+	}
+	if err := s.CheckAddress("www.example.com", net.ParseIP("10.0.5.9")); err == nil {
+		t.Error("expected an in-scope hostname resolving into an out-of-scope range to be blocked")
+	}
+}
+
+type staticResolver []net.IPAddr
+
+func (r staticResolver) LookupIPAddr(context.Context, string) ([]net.IPAddr, error) {
+	return r, nil
+}
+
+func TestResolver(t *testing.T) {
+	s, err := New(nil, []string{"192.168.0.0/16"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mixed := staticResolver{{IP: net.ParseIP("192.168.1.10")}, {IP: net.ParseIP("203.0.113.7")}}
+	resolver := &Resolver{Resolver: mixed, Scope: s}
+	addrs, err := resolver.LookupIPAddr(context.Background(), "mixed.example.com")
+	if err != nil || len(addrs) != 1 || !addrs[0].IP.Equal(net.ParseIP("203.0.113.7")) {
+		t.Errorf("expected only the public address, got %v (%v)", addrs, err)
+	}
+	// the inner resolver's addresses are left as they were
+	if !mixed[0].IP.Equal(net.ParseIP("192.168.1.10")) {
+		t.Errorf("expected the inner resolver's slice to be left untouched, got %v", mixed)
+	}
+
+	resolver.Resolver = staticResolver{{IP: net.ParseIP("192.168.1.10")}}
+	_, err = resolver.LookupIPAddr(context.Background(), "internal.example.com")
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) || blocked.Host != "internal.example.com" {
+		t.Errorf("expected a BlockedError, got %v", err)
+	}
+}
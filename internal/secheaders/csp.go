@@ -0,0 +1,66 @@
+package secheaders
+
+import "strings"
+
+// CSP maps each directive of a Content-Security-Policy to its source list
+type CSP map[string][]string
+
+// fetchDirectives fall back to default-src when they are not set
+var fetchDirectives = map[string]bool{
+	"script-src":  true,
+	"style-src":   true,
+	"img-src":     true,
+	"connect-src": true,
+	"font-src":    true,
+	"object-src":  true,
+	"media-src":   true,
+	"frame-src":   true,
+	"worker-src":  true,
+}
+
+// ParseCSP parses a Content-Security-Policy header value, returning nil when it is empty
+func ParseCSP(value string) CSP {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+
+	csp := make(CSP)
+	for _, directive := range strings.Split(value, ";") {
+		fields := strings.Fields(directive)
+		if len(fields) == 0 {
+			continue
+		}
+
+		name := strings.ToLower(fields[0])
+		// only the first occurrence of a directive is enforced
+		if _, exists := csp[name]; !exists {
+			csp[name] = fields[1:]
+		}
+	}
+	return csp
+}
+
+// Sources returns the effective source list of a directive, applying the default-src fallback
+func (c CSP) Sources(directive string) ([]string, bool) {
+	if sources, ok := c[directive]; ok {
+		return sources, true
+	}
+	if fetchDirectives[directive] {
+		sources, ok := c["default-src"]
+		return sources, ok
+	}
+	return nil, false
+}
+
+// hasNonceOrHash reports whether the directive uses nonces or hashes, which make browsers ignore 'unsafe-inline'
+func (c CSP) hasNonceOrHash(directive string) bool {
+	sources, _ := c.Sources(directive)
+	for _, source := range sources {
+		source = strings.ToLower(source)
+		if strings.HasPrefix(source, "'nonce-") || strings.HasPrefix(source, "'sha256-") ||
+			strings.HasPrefix(source, "'sha384-") || strings.HasPrefix(source, "'sha512-") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,241 @@
+package secheaders
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Finding severities and the score each one deducts
+const (
+	SeverityHigh   = "high"
+	SeverityMedium = "medium"
+	SeverityLow    = "low"
+	SeverityInfo   = "info"
+)
+
+var severityPenalty = map[string]int{
+	SeverityHigh:   20,
+	SeverityMedium: 10,
+	SeverityLow:    5,
+	SeverityInfo:   0,
+}
+
+const (
+	// HSTS max-age below six months is considered too short
+	minHSTSMaxAge = 15768000
+	// HSTS preload lists require at least one year
+	preloadHSTSMaxAge = 31536000
+)
+
+// Finding is a single issue found in a response's security headers
+type Finding struct {
+	Header   string `json:"header"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// Report is the security headers evaluation of a single response
+type Report struct {
+	Grade    string    `json:"grade"`
+	Score    int       `json:"score"`
+	Findings []Finding `json:"findings,omitempty"`
+}
+
+// Audit evaluates the security headers returned by header, which looks a header up by name.
+// HSTS is only expected on HTTPS responses.
+func Audit(header func(name string) string, isHTTPS bool) *Report {
+	report := &Report{}
+
+	if isHTTPS {
+		report.auditHSTS(header("Strict-Transport-Security"))
+	}
+
+	csp := ParseCSP(header("Content-Security-Policy"))
+	report.auditCSP(csp, header("Content-Security-Policy-Report-Only"))
+	report.auditFraming(header("X-Frame-Options"), csp)
+	report.auditContentTypeOptions(header("X-Content-Type-Options"))
+	report.auditReferrerPolicy(header("Referrer-Policy"))
+
+	if header("Permissions-Policy") == "" {
+		report.add("Permissions-Policy", SeverityLow, "missing")
+	}
+	if header("Cross-Origin-Opener-Policy") == "" {
+		report.add("Cross-Origin-Opener-Policy", SeverityLow, "missing")
+	}
+	if header("Cross-Origin-Embedder-Policy") == "" {
+		report.add("Cross-Origin-Embedder-Policy", SeverityInfo, "missing")
+	}
+	if header("Cross-Origin-Resource-Policy") == "" {
+		report.add("Cross-Origin-Resource-Policy", SeverityInfo, "missing")
+	}
+
+	report.grade()
+	return report
+}
+
+func (r *Report) add(header, severity, message string) {
+	r.Findings = append(r.Findings, Finding{Header: header, Severity: severity, Message: message})
+}
+
+// grade computes the score from the findings and maps it to a letter grade
+func (r *Report) grade() {
+	r.Score = 100
+	for _, finding := range r.Findings {
+		r.Score -= severityPenalty[finding.Severity]
+	}
+	r.Score = max(r.Score, 0)
+
+	switch {
+	case r.Score >= 90:
+		r.Grade = "A"
+	case r.Score >= 80:
+		r.Grade = "B"
+	case r.Score >= 70:
+		r.Grade = "C"
+	case r.Score >= 60:
+		r.Grade = "D"
+	default:
+		r.Grade = "F"
+	}
+}
+
+func (r *Report) auditHSTS(value string) {
+	const name = "Strict-Transport-Security"
+	if value == "" {
+		r.add(name, SeverityHigh, "missing")
+		return
+	}
+
+	maxAge := -1
+	includeSubDomains, preload := false, false
+	for _, directive := range strings.Split(value, ";") {
+		key, val, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(key) {
+		case "max-age":
+			if age, err := strconv.Atoi(strings.Trim(val, `"`)); err == nil {
+				maxAge = age
+			}
+		case "includesubdomains":
+			includeSubDomains = true
+		case "preload":
+			preload = true
+		}
+	}
+
+	switch {
+	case maxAge < 0:
+		r.add(name, SeverityHigh, "missing or invalid max-age")
+		return
+	case maxAge == 0:
+		r.add(name, SeverityHigh, "max-age=0 disables HSTS")
+		return
+	case maxAge < minHSTSMaxAge:
+		r.add(name, SeverityMedium, "max-age shorter than six months")
+	}
+
+	if !includeSubDomains {
+		r.add(name, SeverityLow, "includeSubDomains not set")
+	}
+	if preload && (!includeSubDomains || maxAge < preloadHSTSMaxAge) {
+		r.add(name, SeverityLow, "preload set but requirements not met (includeSubDomains and max-age of one year)")
+	}
+}
+
+func (r *Report) auditCSP(csp CSP, reportOnly string) {
+	const name = "Content-Security-Policy"
+	if csp == nil {
+		if reportOnly != "" {
+			r.add(name, SeverityHigh, "only sent as Report-Only, not enforced")
+		} else {
+			r.add(name, SeverityHigh, "missing")
+		}
+		return
+	}
+
+	scriptSources, hasScriptPolicy := csp.Sources("script-src")
+	if !hasScriptPolicy {
+		r.add(name, SeverityMedium, "no script-src or default-src directive")
+	}
+
+	for _, source := range scriptSources {
+		lower := strings.ToLower(source)
+		// host sources may be scheme qualified, https://* allows any host like *
+		host := strings.TrimPrefix(strings.TrimPrefix(lower, "https://"), "http://")
+		switch {
+		case lower == "'unsafe-inline'":
+			if !csp.hasNonceOrHash("script-src") {
+				r.add(name, SeverityHigh, "script-src allows 'unsafe-inline'")
+			}
+		case lower == "'unsafe-eval'":
+			r.add(name, SeverityMedium, "script-src allows 'unsafe-eval'")
+		case host == "*", lower == "http:", lower == "https:":
+			r.add(name, SeverityHigh, "script-src allows any host ("+source+")")
+		case lower == "data:":
+			r.add(name, SeverityMedium, "script-src allows data: URIs")
+		case strings.HasPrefix(host, "*."):
+			r.add(name, SeverityMedium, "script-src allows every subdomain of a host ("+source+")")
+		}
+	}
+
+	if objectSources, ok := csp.Sources("object-src"); !ok || !isNone(objectSources) {
+		r.add(name, SeverityLow, "object-src not restricted to 'none'")
+	}
+	if _, ok := csp["base-uri"]; !ok {
+		r.add(name, SeverityLow, "base-uri missing")
+	}
+	if sources, ok := csp["default-src"]; ok {
+		for _, source := range sources {
+			if source == "*" {
+				r.add(name, SeverityMedium, "default-src allows any host (*)")
+			}
+		}
+	}
+}
+
+func (r *Report) auditFraming(xfo string, csp CSP) {
+	if _, ok := csp["frame-ancestors"]; ok {
+		return
+	}
+
+	const name = "X-Frame-Options"
+	switch strings.ToUpper(strings.TrimSpace(xfo)) {
+	case "DENY", "SAMEORIGIN":
+	case "":
+		r.add(name, SeverityMedium, "missing, and no CSP frame-ancestors (clickjacking)")
+	default:
+		if strings.HasPrefix(strings.ToUpper(xfo), "ALLOW-FROM") {
+			r.add(name, SeverityLow, "ALLOW-FROM is not supported by modern browsers")
+		} else {
+			r.add(name, SeverityMedium, "invalid value "+xfo)
+		}
+	}
+}
+
+func (r *Report) auditContentTypeOptions(value string) {
+	const name = "X-Content-Type-Options"
+	switch {
+	case value == "":
+		r.add(name, SeverityLow, "missing")
+	case !strings.EqualFold(strings.TrimSpace(value), "nosniff"):
+		r.add(name, SeverityLow, "invalid value "+value)
+	}
+}
+
+func (r *Report) auditReferrerPolicy(value string) {
+	const name = "Referrer-Policy"
+	if value == "" {
+		r.add(name, SeverityLow, "missing")
+		return
+	}
+
+	// the last recognized policy in the list wins
+	policies := strings.Split(value, ",")
+	policy := strings.ToLower(strings.TrimSpace(policies[len(policies)-1]))
+	if policy == "unsafe-url" || policy == "no-referrer-when-downgrade" {
+		r.add(name, SeverityLow, policy+" leaks full URLs to other origins")
+	}
+}
+
+func isNone(sources []string) bool {
+	return len(sources) == 1 && sources[0] == "'none'"
+}
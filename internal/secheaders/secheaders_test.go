@@ -0,0 +1,133 @@
+package secheaders
+
+import (
+	"testing"
+)
+
+func TestAudit(t *testing.T) {
+	testCases := []struct {
+		name    string
+		headers map[string]string
+		isHTTPS bool
+		grade   string
+		// header restricts findings and absent to the findings of one header
+		header   string
+		findings []string
+		absent   []string
+	}{
+		{
+			name: "Hardened response",
+			headers: map[string]string{
+				"Strict-Transport-Security":    "max-age=63072000; includeSubDomains; preload",
+				"Content-Security-Policy":      "default-src 'self'; object-src 'none'; base-uri 'self'; frame-ancestors 'none'",
+				"X-Content-Type-Options":       "nosniff",
+				"Referrer-Policy":              "strict-origin-when-cross-origin",
+				"Permissions-Policy":           "geolocation=()",
+				"Cross-Origin-Opener-Policy":   "same-origin",
+				"Cross-Origin-Embedder-Policy": "require-corp",
+				"Cross-Origin-Resource-Policy": "same-origin",
+			},
+			isHTTPS: true,
+			grade:   "A",
+		},
+		{
+			name: "Weak CSP and HSTS",
+			headers: map[string]string{
+				"Strict-Transport-Security":  "max-age=3600",
+				"Content-Security-Policy":    "default-src *; script-src 'self' 'unsafe-inline' 'unsafe-eval'",
+				"X-Frame-Options":            "DENY",
+				"X-Content-Type-Options":     "nosniff",
+				"Referrer-Policy":            "no-referrer",
+				"Permissions-Policy":         "camera=()",
+				"Cross-Origin-Opener-Policy": "same-origin",
+			},
+			isHTTPS: true,
+			findings: []string{
+				"max-age shorter than six months",
+				"includeSubDomains not set",
+				"script-src allows 'unsafe-inline'",
+				"script-src allows 'unsafe-eval'",
+				"default-src allows any host (*)",
+			},
+		},
+		{
+			name: "Nonce makes unsafe-inline ignored",
+			headers: map[string]string{
+				"Content-Security-Policy": "script-src 'nonce-abc' 'unsafe-inline'; object-src 'none'; base-uri 'none'",
+			},
+			absent: []string{"script-src allows 'unsafe-inline'"},
+		},
+		{
+			name: "Scheme and wildcard script sources",
+			headers: map[string]string{
+				"Content-Security-Policy": "default-src 'self'; script-src 'self' https: data: *.cdn.example.com https://*.googleapis.com",
+			},
+			findings: []string{
+				"script-src allows any host (https:)",
+				"script-src allows data: URIs",
+				"script-src allows every subdomain of a host (*.cdn.example.com)",
+				"script-src allows every subdomain of a host (https://*.googleapis.com)",
+			},
+		},
+		{
+			name: "Scheme qualified wildcard",
+			headers: map[string]string{
+				"Content-Security-Policy": "script-src https://*",
+			},
+			findings: []string{"script-src allows any host (https://*)"},
+		},
+		{
+			// the loop below checks that no Strict-Transport-Security finding is reported over HTTP
+			name: "HSTS not expected over HTTP",
+			headers: map[string]string{
+				"Strict-Transport-Security": "max-age=0",
+			},
+			absent: []string{"max-age=0 disables HSTS"},
+		},
+		{
+			name:     "HSTS expected over HTTPS",
+			headers:  map[string]string{},
+			isHTTPS:  true,
+			header:   "Strict-Transport-Security",
+			findings: []string{"missing"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			report := Audit(func(name string) string { return tc.headers[name] }, tc.isHTTPS)
+
+			if tc.grade != "" && report.Grade != tc.grade {
+				t.Errorf("expected grade %s, got %s (%+v)", tc.grade, report.Grade, report.Findings)
+			}
+
+			for _, message := range tc.findings {
+				if !hasFinding(report, tc.header, message) {
+					t.Errorf("expected finding %q, got %+v", message, report.Findings)
+				}
+			}
+
+			for _, message := range tc.absent {
+				if hasFinding(report, tc.header, message) {
+					t.Errorf("unexpected finding %q", message)
+				}
+			}
+
+			for _, finding := range report.Findings {
+				if finding.Header == "Strict-Transport-Security" && !tc.isHTTPS {
+					t.Error("unexpected HSTS finding over HTTP")
+				}
+			}
+		})
+	}
+}
+
+// hasFinding reports whether report has a finding with message, for the given header unless it is empty
+func hasFinding(report *Report, header, message string) bool {
+	for _, finding := range report.Findings {
+		if finding.Message == message && (header == "" || finding.Header == header) {
+			return true
+		}
+	}
+	return false
+}
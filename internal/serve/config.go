@@ -0,0 +1,81 @@
+package serve
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+)
+
+// Module is a named set of probe options, selected with the module parameter of /probe
+type Module struct {
+	// Prober is "http" or "dns"
+	Prober string `json:"prober"`
+	// Timeout in seconds for each request or DNS record's resolution
+	Timeout int    `json:"timeout"`
+	Method  string `json:"method"`
+	HTTP2   bool   `json:"http2"`
+	// IPProtocol restricts connections and lookups to "ip4" or "ip6"
+	IPProtocol string `json:"ip_protocol"`
+	// ValidStatusCodes are the status codes that make a probe successful, any 2xx when empty
+	ValidStatusCodes []int `json:"valid_status_codes"`
+	SecurityHeaders  bool  `json:"security_headers"`
+}
+
+// Config holds the modules of the server
+type Config struct {
+	Modules map[string]Module `json:"modules"`
+}
+
+// DefaultConfig is used when no config file is given
+func DefaultConfig() *Config {
+	return &Config{Modules: map[string]Module{
+		"http_2xx": {Prober: "http", Timeout: 10, Method: "GET"},
+		"dns":      {Prober: "dns", Timeout: 10},
+	}}
+}
+
+// LoadConfig reads the modules from a JSON config file, keeping the default modules it doesn't redefine
+func LoadConfig(filename string) (*Config, error) {
+	config := DefaultConfig()
+	if filename == "" {
+		return config, nil
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file %s: %w", filename, err)
+	}
+	var loaded Config
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return nil, fmt.Errorf("[!] invalid config file %s: %w", filename, err)
+	}
+
+	for name, module := range loaded.Modules {
+		if module.Prober == "" {
+			module.Prober = "http"
+		}
+		if module.Timeout <= 0 {
+			module.Timeout = 10
+		}
+		if module.Method == "" {
+			module.Method = "GET"
+		}
+		if !slices.Contains([]string{"http", "dns"}, module.Prober) {
+			return nil, fmt.Errorf("[!] invalid prober %q for module %s, expected http or dns", module.Prober, name)
+		}
+		if !slices.Contains([]string{"", "ip4", "ip6"}, module.IPProtocol) {
+			return nil, fmt.Errorf("[!] invalid ip_protocol %q for module %s, expected ip4 or ip6", module.IPProtocol, name)
+		}
+		config.Modules[name] = module
+	}
+	return config, nil
+}
+
+// validStatus reports whether a status code makes a probe of the module successful
+func (m Module) validStatus(code int) bool {
+	if len(m.ValidStatusCodes) == 0 {
+		return code >= 200 && code < 300
+	}
+	return slices.Contains(m.ValidStatusCodes, code)
+}
@@ -0,0 +1,73 @@
+package serve
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// metrics collects gauges and writes them in the Prometheus text exposition format
+type metrics struct {
+	families []*family
+}
+
+type family struct {
+	name    string
+	help    string
+	samples []sample
+}
+
+type sample struct {
+	labels string
+	value  float64
+}
+
+// gauge adds a sample to the named gauge, with labels given as name/value pairs
+func (m *metrics) gauge(name, help string, value float64, labels ...string) {
+	var f *family
+	for _, existing := range m.families {
+		if existing.name == name {
+			f = existing
+			break
+		}
+	}
+	if f == nil {
+		f = &family{name: name, help: help}
+		m.families = append(m.families, f)
+	}
+
+	var pairs []string
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, labels[i]+"="+strconv.Quote(labels[i+1]))
+	}
+	var formatted string
+	if len(pairs) > 0 {
+		formatted = "{" + strings.Join(pairs, ",") + "}"
+	}
+	f.samples = append(f.samples, sample{labels: formatted, value: value})
+}
+
+// boolGauge adds a gauge that is 1 when value is true and 0 otherwise
+func (m *metrics) boolGauge(name, help string, value bool, labels ...string) {
+	var f float64
+	if value {
+		f = 1
+	}
+	m.gauge(name, help, f, labels...)
+}
+
+func (m *metrics) write(w io.Writer) error {
+	for _, f := range m.families {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", f.name, f.help, f.name); err != nil {
+			return err
+		}
+		for _, s := range f.samples {
+			value := strconv.FormatFloat(s.value, 'g', -1, 64)
+			if _, err := fmt.Fprintf(w, "%s%s %s\n", f.name, s.labels, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+package serve
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GraveSIN/http-probe/internal/dnsprobe"
+	"github.com/GraveSIN/http-probe/internal/probe"
+	"github.com/GraveSIN/http-probe/internal/validator"
+)
+
+// scrapeTimeoutOffset is left out of the Prometheus scrape timeout so metrics are written before it
+const scrapeTimeoutOffset = 500 * time.Millisecond
+
+// Server answers /probe requests with Prometheus metrics, like the blackbox exporter
+type Server struct {
+	config *Config
+
+	// DNS probers hold no per-host state, so they are built on first use and reused, one per module
+	mu         sync.Mutex
+	dnsProbers map[string]*dnsprobe.DNSProber
+}
+
+func New(config *Config) *Server {
+	return &Server{
+		config:     config,
+		dnsProbers: make(map[string]*dnsprobe.DNSProber),
+	}
+}
+
+// ListenAndServe serves /probe on addr until the listener fails
+func (s *Server) ListenAndServe(addr string) error {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return server.ListenAndServe()
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/probe", s.handleProbe)
+	mux.HandleFunc("/", s.handleIndex)
+	return mux
+}
+
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	names := make([]string, 0, len(s.config.Modules))
+	for name := range s.config.Modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "http-probe exporter, probe with /probe?target=<target>&module=<module>")
+	fmt.Fprintln(w, "modules:")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s (%s)\n", name, s.config.Modules[name].Prober)
+	}
+}
+
+func (s *Server) handleProbe(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	target := query.Get("target")
+	if target == "" {
+		http.Error(w, "target parameter is missing", http.StatusBadRequest)
+		return
+	}
+	name := cmp.Or(query.Get("module"), "http_2xx")
+	module, ok := s.config.Modules[name]
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown module %q", name), http.StatusBadRequest)
+		return
+	}
+
+	timeout := probeTimeout(r, module)
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	m := &metrics{}
+	start := time.Now()
+	var success bool
+	if module.Prober == "dns" {
+		success = s.probeDNS(ctx, m, name, module, target)
+	} else {
+		success = probeHTTP(ctx, m, module, target, timeout)
+	}
+	m.gauge("probe_duration_seconds", "How long the probe took to complete in seconds", time.Since(start).Seconds())
+	m.boolGauge("probe_success", "Whether the probe was a success", success)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.write(w)
+}
+
+// probeTimeout returns how long a probe may take: the module's timeout, shortened to fit the
+// scrape timeout Prometheus sends in X-Prometheus-Scrape-Timeout-Seconds
+func probeTimeout(r *http.Request, module Module) time.Duration {
+	timeout := time.Duration(module.Timeout) * time.Second
+	seconds, err := strconv.ParseFloat(r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds"), 64)
+	if err != nil || seconds <= 0 {
+		return timeout
+	}
+
+	scrapeTimeout := time.Duration(seconds * float64(time.Second))
+	if scrapeTimeout > scrapeTimeoutOffset {
+		scrapeTimeout -= scrapeTimeoutOffset
+	}
+	return min(timeout, scrapeTimeout)
+}
+
+// probeHTTP probes target with a prober of its own and adds the HTTP, timing and TLS metrics.
+// The prober is built per scrape so the per-host state it caches (soft-404 baselines, ALPN,
+// pinned clients, ...) never outlives the scrape. Only failure is reported when ctx is done first.
+func probeHTTP(ctx context.Context, m *metrics, module Module, target string, timeout time.Duration) bool {
+	urls, err := validator.ConvertDomainsToURLsAndReturnValidURLs(&[]string{target})
+	if err != nil || len(urls) == 0 {
+		return false
+	}
+	prober := probe.NewProber(&probe.ProberConfig{
+		URLs:    &[]string{},
+		Threads: 1,
+		// the prober's timeout is in whole seconds, ctx enforces the exact one
+		Timeout:         max(1, int(timeout/time.Second)),
+		Method:          module.Method,
+		HTTP2:           module.HTTP2,
+		IPNetwork:       module.IPProtocol,
+		SecurityHeaders: module.SecurityHeaders,
+	})
+
+	type outcome struct {
+		timings probe.ConnectionTimings
+		result  probe.ProbeResult
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		var o outcome
+		// the phases are timed on a connection of their own before the request
+		o.timings, _ = prober.Timings(urls[0])
+		if results := prober.Probe(urls[0]); len(results) > 0 {
+			o.result = results[0]
+		}
+		done <- o
+	}()
+
+	var o outcome
+	select {
+	case <-ctx.Done():
+		return false
+	case o = <-done:
+	}
+	timings, result := o.timings, o.result
+
+	m.gauge("probe_dns_lookup_time_seconds", "Returns the time taken for probe dns lookup in seconds", timings.Resolve.Seconds())
+	if timings.IP != nil {
+		protocol := 6
+		if timings.IP.To4() != nil {
+			protocol = 4
+		}
+		m.gauge("probe_ip_protocol", "Specifies whether probe ip protocol is IP4 or IP6", float64(protocol))
+	}
+
+	phases := "Duration of http request by phase"
+	m.gauge("probe_http_duration_seconds", phases, timings.Resolve.Seconds(), "phase", "resolve")
+	m.gauge("probe_http_duration_seconds", phases, timings.Connect.Seconds(), "phase", "connect")
+	m.gauge("probe_http_duration_seconds", phases, timings.TLS.Seconds(), "phase", "tls")
+	m.gauge("probe_http_duration_seconds", phases, result.TimeTaken.Seconds(), "phase", "processing")
+
+	m.gauge("probe_http_status_code", "Response HTTP status code", float64(result.StatusCode))
+	m.gauge("probe_http_content_length", "Length of http content response", float64(result.ContentLength))
+	if version, ok := httpVersion(result.Protocol); ok {
+		m.gauge("probe_http_version", "Returns the version of HTTP of the probe response", version)
+	}
+	// the phases were timed over HTTPS even when the request fell back to plain HTTP, the result's
+	// URL tells which one answered
+	ssl := strings.HasPrefix(result.URL, "https://")
+	m.boolGauge("probe_http_ssl", "Indicates if SSL was used for the final request", ssl)
+	if ssl && timings.TLSVersion != "" {
+		m.gauge("probe_tls_version_info", "Returns the TLS version used", 1, "version", timings.TLSVersion)
+	}
+	if ssl && !timings.CertificateExpiry.IsZero() {
+		m.gauge("probe_ssl_earliest_cert_expiry", "Returns last SSL chain expiry in unixtime", float64(timings.CertificateExpiry.Unix()))
+	}
+	if result.SecurityHeaders != nil {
+		m.gauge("probe_http_security_headers_score", "Score of the security headers audit", float64(result.SecurityHeaders.Score))
+	}
+
+	return result.StatusLine != "" && module.validStatus(result.StatusCode)
+}
+
+// httpVersion converts the protocol of a result, "HTTP/1.1", "h2" or "h2c", to a version number
+func httpVersion(protocol string) (float64, bool) {
+	if strings.HasPrefix(protocol, "h2") {
+		return 2, true
+	}
+	version, err := strconv.ParseFloat(strings.TrimPrefix(protocol, "HTTP/"), 64)
+	return version, err == nil
+}
+
+// probeDNS looks up the records of target with the module's prober and adds the record counts.
+// Only failure is reported when ctx is done first.
+func (s *Server) probeDNS(ctx context.Context, m *metrics, name string, module Module, target string) bool {
+	domain := target
+	if parsed, err := url.Parse(target); err == nil && parsed.Host != "" {
+		domain = parsed.Hostname()
+	}
+	prober := s.dnsProber(name, module)
+
+	start := time.Now()
+	done := make(chan dnsprobe.DNSProbeResult, 1)
+	go func() {
+		done <- prober.ProbeDomain(domain)
+	}()
+
+	var result dnsprobe.DNSProbeResult
+	select {
+	case <-ctx.Done():
+		return false
+	case result = <-done:
+	}
+	m.gauge("probe_dns_lookup_time_seconds", "Returns the time taken for probe dns lookup in seconds", time.Since(start).Seconds())
+
+	records := "Number of records of each type"
+	m.gauge("probe_dns_answer_rrs", records, float64(len(result.ARecords)), "type", "A")
+	m.gauge("probe_dns_answer_rrs", records, float64(len(result.AAAARecords)), "type", "AAAA")
+	m.gauge("probe_dns_answer_rrs", records, float64(len(result.NSRecords)), "type", "NS")
+	m.gauge("probe_dns_answer_rrs", records, float64(len(result.MXRecords)), "type", "MX")
+	m.gauge("probe_dns_answer_rrs", records, float64(len(result.TXTRecords)), "type", "TXT")
+
+	return len(result.ARecords)+len(result.AAAARecords) > 0
+}
+
+func (s *Server) dnsProber(name string, module Module) *dnsprobe.DNSProber {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if prober, ok := s.dnsProbers[name]; ok {
+		return prober
+	}
+	prober := dnsprobe.NewDNSProber(&dnsprobe.DNSProbeConfig{
+		Domains:   &[]string{},
+		Threads:   1,
+		Timeout:   module.Timeout,
+		IPNetwork: module.IPProtocol,
+	})
+	s.dnsProbers[name] = prober
+	return prober
+}
@@ -0,0 +1,233 @@
+package serve
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleProbe(t *testing.T) {
+	target := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		fmt.Fprint(w, "<html><title>Target</title></html>")
+	}))
+	target.Config.ErrorLog = log.New(io.Discard, "", 0)
+	defer target.Close()
+
+	config := DefaultConfig()
+	config.Modules["http_404"] = Module{Prober: "http", Timeout: 5, Method: "GET", ValidStatusCodes: []int{404}}
+	server := httptest.NewServer(New(config).Handler())
+	defer server.Close()
+
+	tests := []struct {
+		query    string
+		status   int
+		expected []string
+	}{
+		{"target=" + target.URL, http.StatusOK, []string{
+			"probe_success 1",
+			"probe_http_status_code 200",
+			"probe_http_ssl 1",
+			`probe_http_duration_seconds{phase="tls"}`,
+			"# TYPE probe_ssl_earliest_cert_expiry gauge",
+		}},
+		{"module=http_404&target=" + target.URL + "/missing", http.StatusOK, []string{"probe_success 1", "probe_http_status_code 404"}},
+		{"module=http_404&target=" + target.URL, http.StatusOK, []string{"probe_success 0", "probe_http_status_code 200"}},
+		{"module=unknown&target=" + target.URL, http.StatusBadRequest, []string{`unknown module "unknown"`}},
+		{"", http.StatusBadRequest, []string{"target parameter is missing"}},
+	}
+
+	for _, test := range tests {
+		resp, err := http.Get(server.URL + "/probe?" + test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != test.status {
+			t.Errorf("%s: expected status %d, got %d", test.query, test.status, resp.StatusCode)
+		}
+		for _, expected := range test.expected {
+			if !strings.Contains(string(body), expected) {
+				t.Errorf("%s: expected %q in:\n%s", test.query, expected, body)
+			}
+		}
+	}
+}
+
+// handshakeOnlyListener completes TLS handshakes and hangs up right after, passing plain HTTP
+// connections through, so requests over HTTPS fail and fall back to HTTP on the same port
+type handshakeOnlyListener struct {
+	net.Listener
+	config *tls.Config
+}
+
+func (l *handshakeOnlyListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		peeked := &peekedConn{Conn: conn, reader: bufio.NewReader(conn)}
+		// 0x16 starts a TLS handshake record
+		if first, err := peeked.reader.Peek(1); err == nil && first[0] == 0x16 {
+			go func() {
+				tls.Server(peeked, l.config).Handshake()
+				conn.Close()
+			}()
+			continue
+		}
+		return peeked, nil
+	}
+}
+
+type peekedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *peekedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+func TestHandleProbeHTTPFallback(t *testing.T) {
+	// only borrowed for its certificate
+	certificate := httptest.NewTLSServer(http.NotFoundHandler())
+	certificate.Close()
+
+	target := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html><title>Plain</title></html>")
+	}))
+	target.Listener = &handshakeOnlyListener{Listener: target.Listener, config: certificate.TLS}
+	target.Config.ErrorLog = log.New(io.Discard, "", 0)
+	target.Start()
+	defer target.Close()
+
+	// the TLS phase is timed, but the request is answered over plain HTTP
+	handler := New(DefaultConfig()).Handler()
+	req := httptest.NewRequest(http.MethodGet, "/probe?target=https://"+target.Listener.Addr().String(), nil)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	body := recorder.Body.String()
+	for _, expected := range []string{"probe_success 1", "probe_http_ssl 0"} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("expected %q in:\n%s", expected, body)
+		}
+	}
+	for _, unexpected := range []string{"probe_tls_version_info{", "probe_ssl_earliest_cert_expiry "} {
+		if strings.Contains(body, unexpected) {
+			t.Errorf("expected no %q after the fallback in:\n%s", unexpected, body)
+		}
+	}
+}
+
+func TestHandleProbeTimeout(t *testing.T) {
+	release := make(chan struct{})
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer target.Close()
+	defer close(release)
+
+	config := DefaultConfig()
+	config.Modules["slow"] = Module{Prober: "http", Timeout: 30, Method: "GET"}
+	handler := New(config).Handler()
+
+	// the probe gives up at the scrape timeout instead of the module's 30 seconds
+	req := httptest.NewRequest(http.MethodGet, "/probe?module=slow&target="+target.URL, nil)
+	req.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", "1")
+	recorder := httptest.NewRecorder()
+	start := time.Now()
+	handler.ServeHTTP(recorder, req)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the probe to stop at the scrape timeout, took %s", elapsed)
+	}
+	if !strings.Contains(recorder.Body.String(), "probe_success 0") {
+		t.Errorf("expected probe_success 0 in:\n%s", recorder.Body)
+	}
+
+	// a scrape Prometheus gave up on is not probed to the end
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req = httptest.NewRequestWithContext(ctx, http.MethodGet, "/probe?module=slow&target="+target.URL, nil)
+	recorder = httptest.NewRecorder()
+	start = time.Now()
+	handler.ServeHTTP(recorder, req)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the probe to stop with the request, took %s", elapsed)
+	}
+	if !strings.Contains(recorder.Body.String(), "probe_success 0") {
+		t.Errorf("expected probe_success 0 in:\n%s", recorder.Body)
+	}
+}
+
+func TestProbeTimeout(t *testing.T) {
+	module := Module{Timeout: 5}
+	tests := []struct {
+		header   string
+		expected time.Duration
+	}{
+		{"", 5 * time.Second},
+		{"invalid", 5 * time.Second},
+		{"0", 5 * time.Second},
+		{"10", 5 * time.Second},
+		{"3", 2500 * time.Millisecond},
+		{"0.4", 400 * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/probe", nil)
+		if test.header != "" {
+			req.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", test.header)
+		}
+		if timeout := probeTimeout(req, module); timeout != test.expected {
+			t.Errorf("%q: expected %s, got %s", test.header, test.expected, timeout)
+		}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		filename := filepath.Join(dir, "modules.json")
+		if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return filename
+	}
+
+	config, err := LoadConfig(write(`{"modules": {"h2": {"http2": true}, "dns6": {"prober": "dns", "ip_protocol": "ip6"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h2 := config.Modules["h2"]; h2.Prober != "http" || h2.Method != "GET" || h2.Timeout != 10 || !h2.HTTP2 {
+		t.Errorf("expected defaults to be filled in, got %+v", h2)
+	}
+	if _, ok := config.Modules["http_2xx"]; !ok {
+		t.Error("expected the default modules to be kept")
+	}
+
+	for _, content := range []string{`{"modules": {"x": {"prober": "tcp"}}}`, `{"modules": {"x": {"ip_protocol": "ip5"}}}`, `not json`} {
+		if _, err := LoadConfig(write(content)); err == nil {
+			t.Errorf("expected an error for %s", content)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// Pool holds the local addresses probes are sent from, handed out in round-robin
+type Pool struct {
+	addrs []net.IP
+	next  atomic.Uint64
+}
+
+// New builds the pool from source IPs and the addresses of a network interface.
+// It returns nil when both are empty, so the system picks the source address.
+func New(ips []string, iface string) (*Pool, error) {
+	if len(ips) == 0 && iface == "" {
+		return nil, nil
+	}
+
+	pool := &Pool{}
+	for _, s := range ips {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, fmt.Errorf("[!] invalid source IP: %s", s)
+		}
+		pool.addrs = append(pool.addrs, ip)
+	}
+
+	if iface != "" {
+		addrs, err := interfaceAddrs(iface)
+		if err != nil {
+			return nil, err
+		}
+		pool.addrs = append(pool.addrs, addrs...)
+	}
+	return pool, nil
+}
+
+// interfaceAddrs returns the addresses of the interface that can be bound to without a zone
+func interfaceAddrs(name string) ([]net.IP, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("[!] invalid interface %s: %w", name, err)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("[!] error reading the addresses of %s: %w", name, err)
+	}
+
+	var ips []net.IP
+	for _, addr := range addrs {
+		if network, ok := addr.(*net.IPNet); ok && !network.IP.IsLinkLocalUnicast() {
+			ips = append(ips, network.IP)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("[!] interface %s has no usable address", name)
+	}
+	return ips, nil
+}
+
+// Next returns the next source address of the same family as remote, or nil when there is none
+func (p *Pool) Next(remote net.IP) net.IP {
+	ipv4 := remote.To4() != nil
+	var candidates []net.IP
+	for _, addr := range p.addrs {
+		if (addr.To4() != nil) == ipv4 {
+			candidates = append(candidates, addr)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	return candidates[(p.next.Add(1)-1)%uint64(len(candidates))]
+}
+
+// Dialer returns a dialer bound to the next source address for remote. The dialer is unbound
+// when the pool is nil.
+func (p *Pool) Dialer(network string, remote net.IP, timeout time.Duration) (*net.Dialer, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	if p == nil {
+		return dialer, nil
+	}
+
+	local := p.Next(remote)
+	if local == nil {
+		return nil, fmt.Errorf("no source address for %s", remote)
+	}
+	switch network {
+	case "udp", "udp4", "udp6":
+		dialer.LocalAddr = &net.UDPAddr{IP: local}
+	default:
+		dialer.LocalAddr = &net.TCPAddr{IP: local}
+	}
+	return dialer, nil
+}
+
+// ResolverDial returns a net.Resolver Dial func that queries server from the pool's addresses
+func (p *Pool) ResolverDial(server string, timeout time.Duration) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, _, _ := net.SplitHostPort(server)
+		dialer, err := p.Dialer("udp", net.ParseIP(host), timeout)
+		if err != nil {
+			return nil, err
+		}
+		return dialer.DialContext(ctx, "udp", server)
+	}
+}
@@ -0,0 +1,65 @@
+package source
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	pool, err := New([]string{"192.0.2.1", "2001:db8::1", "192.0.2.2"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ipv4 := net.ParseIP("198.51.100.1")
+	for _, expected := range []string{"192.0.2.1", "192.0.2.2", "192.0.2.1"} {
+		if got := pool.Next(ipv4); got.String() != expected {
+			t.Errorf("expected %s, got %s", expected, got)
+		}
+	}
+	if got := pool.Next(net.ParseIP("2001:db8::2")); got.String() != "2001:db8::1" {
+		t.Errorf("expected 2001:db8::1 for an IPv6 remote, got %s", got)
+	}
+
+	ipv4Only, _ := New([]string{"192.0.2.1"}, "")
+	if _, err := ipv4Only.Dialer("tcp", net.ParseIP("2001:db8::2"), time.Second); err == nil {
+		t.Error("expected an error without an IPv6 source address")
+	}
+
+	if _, err := New([]string{"not-an-ip"}, ""); err == nil {
+		t.Error("expected an error for an invalid source IP")
+	}
+	if pool, err := New(nil, ""); pool != nil || err != nil {
+		t.Errorf("expected a nil pool, got %v, %v", pool, err)
+	}
+}
+
+func TestDialerRoundRobin(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	pool, _ := New([]string{"127.0.0.2", "127.0.0.3"}, "")
+	for _, expected := range []string{"127.0.0.2", "127.0.0.3", "127.0.0.2"} {
+		dialer, err := pool.Dialer("tcp", net.ParseIP("127.0.0.1"), time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn, err := dialer.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Skipf("%s is not available: %v", expected, err)
+		}
+		accepted, err := listener.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if host, _, _ := net.SplitHostPort(accepted.RemoteAddr().String()); host != expected {
+			t.Errorf("expected a connection from %s, got %s", expected, host)
+		}
+		accepted.Close()
+		conn.Close()
+	}
+}
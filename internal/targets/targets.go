@@ -0,0 +1,315 @@
+package targets
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"iter"
+	"math"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Range is an inclusive range of IP addresses, expanded one address at a time
+type Range struct {
+	First netip.Addr
+	Last  netip.Addr
+}
+
+// ParseRange parses a CIDR prefix (10.0.0.0/24, 2001:db8::/120), an address range
+// (192.168.1.10-50, 192.168.1.10-192.168.1.50) or a single address.
+// ok is false when s is none of these, such as a hostname or URL.
+func ParseRange(s string) (r Range, ok bool, err error) {
+	s = strings.TrimSpace(s)
+
+	if prefix, err := netip.ParsePrefix(s); err == nil {
+		prefix = prefix.Masked()
+		return Range{First: prefix.Addr(), Last: lastAddr(prefix)}, true, nil
+	}
+
+	if addr, err := netip.ParseAddr(s); err == nil {
+		return Range{First: addr, Last: addr}, true, nil
+	}
+
+	start, end, found := strings.Cut(s, "-")
+	if !found {
+		return Range{}, false, nil
+	}
+	first, err := netip.ParseAddr(start)
+	if err != nil {
+		return Range{}, false, nil
+	}
+
+	last, err := netip.ParseAddr(end)
+	if err != nil {
+		// 192.168.1.10-50 only replaces the last octet
+		octet, convErr := strconv.Atoi(end)
+		if !first.Is4() || convErr != nil || octet < 0 || octet > 255 {
+			return Range{}, true, fmt.Errorf("[!] invalid IP range: %s", s)
+		}
+		bytes := first.As4()
+		bytes[3] = byte(octet)
+		last = netip.AddrFrom4(bytes)
+	}
+
+	if first.BitLen() != last.BitLen() || last.Less(first) {
+		return Range{}, true, fmt.Errorf("[!] invalid IP range: %s", s)
+	}
+	return Range{First: first, Last: last}, true, nil
+}
+
+// lastAddr returns the highest address of the prefix
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	bytes := prefix.Addr().AsSlice()
+	for bit := prefix.Bits(); bit < len(bytes)*8; bit++ {
+		bytes[bit/8] |= 1 << (7 - bit%8)
+	}
+	addr, _ := netip.AddrFromSlice(bytes)
+	return addr
+}
+
+// MaxRangeSize bounds the addresses of the ranges given on one run, so an IPv6 /64 is refused
+// instead of probing until the scan is killed
+const MaxRangeSize = 1 << 24
+
+// Size returns the number of addresses in the range, saturating at math.MaxUint64
+func (r Range) Size() uint64 {
+	first, last := r.First.As16(), r.Last.As16()
+	firstHigh, lastHigh := binary.BigEndian.Uint64(first[:8]), binary.BigEndian.Uint64(last[:8])
+	firstLow, lastLow := binary.BigEndian.Uint64(first[8:]), binary.BigEndian.Uint64(last[8:])
+
+	// when the high halves differ by one the low halves wrapped, and the difference may still fit
+	if lastHigh != firstHigh && (lastHigh != firstHigh+1 || lastLow >= firstLow) {
+		return math.MaxUint64
+	}
+	// a difference of math.MaxUint64 is 2^64 addresses, one more than fits
+	if lastLow-firstLow == math.MaxUint64 {
+		return math.MaxUint64
+	}
+	return lastLow - firstLow + 1
+}
+
+// Contains reports whether addr is in the range
+func (r Range) Contains(addr netip.Addr) bool {
+	return addr.BitLen() == r.First.BitLen() && !addr.Less(r.First) && !r.Last.Less(addr)
+}
+
+// All yields every address of the range in order
+func (r Range) All() iter.Seq[netip.Addr] {
+	return func(yield func(netip.Addr) bool) {
+		for addr := r.First; addr.IsValid(); addr = addr.Next() {
+			if !yield(addr) || addr == r.Last {
+				return
+			}
+		}
+	}
+}
+
+// Expansion holds the address ranges given as targets, the ranges to leave out and the ports to probe
+type Expansion struct {
+	Ranges   []Range
+	Excluded []Range
+	Ports    []string
+}
+
+// URLs yields an HTTPS URL for every address and port that is not excluded.
+// Nothing is materialized, so large prefixes cost no memory.
+func (e *Expansion) URLs() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for _, r := range e.Ranges {
+			for addr := range r.All() {
+				if e.excluded(addr) {
+					continue
+				}
+				for _, url := range WithPorts([]string{"https://" + hostOf(addr)}, e.Ports) {
+					if !yield(url) {
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+func (e *Expansion) excluded(addr netip.Addr) bool {
+	for _, r := range e.Excluded {
+		if r.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func hostOf(addr netip.Addr) string {
+	if addr.Is6() {
+		return "[" + addr.String() + "]"
+	}
+	return addr.String()
+}
+
+// WithPorts combines every URL without an explicit port with each port. URLs are returned as-is when ports is empty.
+func WithPorts(urls []string, ports []string) []string {
+	if len(ports) == 0 {
+		return urls
+	}
+
+	var withPorts []string
+	for _, url := range urls {
+		scheme, rest, _ := strings.Cut(url, "://")
+		host, path, _ := strings.Cut(rest, "/")
+		if _, _, err := net.SplitHostPort(host); err == nil {
+			withPorts = append(withPorts, url)
+			continue
+		}
+		if path != "" {
+			path = "/" + path
+		}
+		for _, port := range ports {
+			withPorts = append(withPorts, scheme+"://"+host+":"+port+path)
+		}
+	}
+	return withPorts
+}
+
+// ParsePorts validates a list of ports
+func ParsePorts(ports []string) ([]string, error) {
+	for _, port := range ports {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return nil, fmt.Errorf("[!] invalid port: %s", port)
+		}
+	}
+	return ports, nil
+}
+
+// ASNPrefixes maps AS numbers (without the AS prefix) to their announced prefixes
+type ASNPrefixes map[string][]string
+
+// ReadASNFile reads an offline ASN to prefix list with one "AS13335 1.1.1.0/24" (or "13335,1.1.1.0/24") entry per line
+func ReadASNFile(filename string) (ASNPrefixes, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading ASN file %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	prefixes := make(ASNPrefixes)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		if len(fields) < 2 {
+			continue
+		}
+		asn, ok := parseASN(fields[0])
+		if !ok {
+			continue
+		}
+		prefixes[asn] = append(prefixes[asn], fields[1])
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error scanning ASN file %s: %w", filename, err)
+	}
+	return prefixes, nil
+}
+
+// parseASN accepts AS13335, as13335 and 13335
+func parseASN(s string) (string, bool) {
+	if len(s) > 2 && strings.EqualFold(s[:2], "AS") {
+		s = s[2:]
+	}
+	if _, err := strconv.ParseUint(s, 10, 32); err != nil {
+		return "", false
+	}
+	return s, true
+}
+
+// IsASN reports whether the input is an AS number such as AS13335
+func IsASN(s string) bool {
+	if len(s) <= 2 || !strings.EqualFold(s[:2], "AS") {
+		return false
+	}
+	_, ok := parseASN(s)
+	return ok
+}
+
+// Split separates the inputs that are address ranges or AS numbers from the hostnames and URLs,
+// and returns the ranges to expand. Bare IPv6 addresses are bracketed so a port can follow them.
+func Split(inputs []string, asnPrefixes ASNPrefixes) (urls []string, ranges []Range, err error) {
+	var size uint64
+	for _, input := range inputs {
+		if IsASN(input) {
+			if asnPrefixes == nil {
+				return nil, nil, fmt.Errorf("[!] %s requires --asn-file", input)
+			}
+			asn, _ := parseASN(input)
+			prefixes, ok := asnPrefixes[asn]
+			if !ok {
+				return nil, nil, fmt.Errorf("[!] %s not found in the ASN file", input)
+			}
+			for _, prefix := range prefixes {
+				r, ok, err := ParseRange(prefix)
+				if err != nil || !ok {
+					return nil, nil, fmt.Errorf("[!] invalid prefix %s for %s", prefix, input)
+				}
+				ranges = append(ranges, r)
+				if size, err = addSize(size, r, input); err != nil {
+					return nil, nil, err
+				}
+			}
+			continue
+		}
+
+		r, ok, err := ParseRange(input)
+		if err != nil {
+			return nil, nil, err
+		}
+		// single addresses keep going through URL validation like hostnames
+		if !ok {
+			urls = append(urls, input)
+			continue
+		}
+		if r.First == r.Last {
+			urls = append(urls, hostOf(r.First))
+			continue
+		}
+		ranges = append(ranges, r)
+		if size, err = addSize(size, r, input); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return urls, ranges, nil
+}
+
+// addSize adds the addresses of r to size, failing above MaxRangeSize
+func addSize(size uint64, r Range, input string) (uint64, error) {
+	if rangeSize := r.Size(); rangeSize <= MaxRangeSize && size+rangeSize <= MaxRangeSize {
+		return size + rangeSize, nil
+	}
+	return 0, fmt.Errorf("[!] %s expands the targets past %d addresses, split the scan into smaller ranges", input, MaxRangeSize)
+}
+
+// ParseExclusions parses the ranges, prefixes and addresses to leave out of expansions
+func ParseExclusions(inputs []string) ([]Range, error) {
+	var excluded []Range
+	for _, input := range inputs {
+		r, ok, err := ParseRange(input)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("[!] invalid exclusion %s, expected an IP address, range or CIDR", input)
+		}
+		excluded = append(excluded, r)
+	}
+	return excluded, nil
+}
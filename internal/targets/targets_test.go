@@ -0,0 +1,141 @@
+package targets
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestParseRange(t *testing.T) {
+	testCases := []struct {
+		input       string
+		first, last string
+		ok          bool
+		err         bool
+	}{
+		{input: "10.0.0.0/30", first: "10.0.0.0", last: "10.0.0.3", ok: true},
+		{input: "10.0.0.7/30", first: "10.0.0.4", last: "10.0.0.7", ok: true},
+		{input: "192.168.1.10-50", first: "192.168.1.10", last: "192.168.1.50", ok: true},
+		{input: "192.168.1.10-192.168.2.5", first: "192.168.1.10", last: "192.168.2.5", ok: true},
+		{input: "2001:db8::/126", first: "2001:db8::", last: "2001:db8::3", ok: true},
+		{input: "10.0.0.1", first: "10.0.0.1", last: "10.0.0.1", ok: true},
+		{input: "192.168.1.50-10", ok: true, err: true},
+		{input: "192.168.1.1-300", ok: true, err: true},
+		{input: "example.com"},
+		{input: "https://10.0.0.0/24"},
+		{input: "my-host.example.com"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			r, ok, err := ParseRange(tc.input)
+			if ok != tc.ok || (err != nil) != tc.err {
+				t.Fatalf("expected ok %t and error %t, got %t and %v", tc.ok, tc.err, ok, err)
+			}
+			if !ok || err != nil {
+				return
+			}
+			if r.First.String() != tc.first || r.Last.String() != tc.last {
+				t.Errorf("expected %s-%s, got %s-%s", tc.first, tc.last, r.First, r.Last)
+			}
+		})
+	}
+}
+
+func TestExpansionURLs(t *testing.T) {
+	ranges, _ := ParseExclusions([]string{"10.0.0.0/30", "2001:db8::1-2001:db8::2"})
+	excluded, _ := ParseExclusions([]string{"10.0.0.1-2"})
+	expansion := &Expansion{Ranges: ranges, Excluded: excluded, Ports: []string{"80", "8443"}}
+
+	expected := []string{
+		"https://10.0.0.0:80", "https://10.0.0.0:8443",
+		"https://10.0.0.3:80", "https://10.0.0.3:8443",
+		"https://[2001:db8::1]:80", "https://[2001:db8::1]:8443",
+		"https://[2001:db8::2]:80", "https://[2001:db8::2]:8443",
+	}
+	if urls := slices.Collect(expansion.URLs()); !slices.Equal(urls, expected) {
+		t.Errorf("expected %v, got %v", expected, urls)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	asnPrefixes := ASNPrefixes{"13335": {"1.1.1.0/24", "1.0.0.0/24"}}
+
+	urls, ranges, err := Split([]string{"example.com", "AS13335", "10.0.0.0/24", "10.0.0.1", "https://example.org:8443/x"}, asnPrefixes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(urls, []string{"example.com", "10.0.0.1", "https://example.org:8443/x"}) {
+		t.Errorf("unexpected URLs %v", urls)
+	}
+	if len(ranges) != 3 {
+		t.Errorf("expected 3 ranges, got %v", ranges)
+	}
+
+	urls, _, err = Split([]string{"2001:db8::1", "[2001:db8::2]", "https://[2001:db8::3]:8443"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"[2001:db8::1]", "[2001:db8::2]", "https://[2001:db8::3]:8443"}; !slices.Equal(urls, expected) {
+		t.Errorf("expected bracketed IPv6 addresses %v, got %v", expected, urls)
+	}
+
+	for _, inputs := range [][]string{{"2001:db8::/64"}, {"2001:db8::1-2001:db8:0:1::"}, {"10.0.0.0/7"}, {"10.0.0.0/9", "10.128.0.0/9", "11.0.0.0/31"}} {
+		if _, _, err := Split(inputs, nil); err == nil {
+			t.Errorf("expected %v to be refused past %d addresses", inputs, MaxRangeSize)
+		}
+	}
+	if _, _, err := Split([]string{"10.0.0.0/9", "10.128.0.0/9"}, nil); err != nil {
+		t.Errorf("expected %d addresses to be accepted, got %v", MaxRangeSize, err)
+	}
+
+	if _, _, err := Split([]string{"AS13335"}, nil); err == nil {
+		t.Error("expected an error for an AS number without an ASN file")
+	}
+	if _, _, err := Split([]string{"AS64500"}, asnPrefixes); err == nil {
+		t.Error("expected an error for an unknown AS number")
+	}
+}
+
+func TestRangeSize(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected uint64
+	}{
+		{"10.0.0.1", 1},
+		{"10.0.0.0/24", 256},
+		{"192.168.1.10-50", 41},
+		{"2001:db8::/120", 256},
+		{"2001:db8::ffff:ffff:ffff:ff00-2001:db8:0:1::ff", 512},
+		{"2001:db8::/64", math.MaxUint64},
+		// exactly 2^64 addresses across the low halves, which would wrap to 0
+		{"2001:db8::1-2001:db8:0:1::", math.MaxUint64},
+		{"2001:db8::ffff:ffff:ffff:ffff-2001:db8:0:1:ffff:ffff:ffff:fffe", math.MaxUint64},
+		{"2001:db8::1-2001:db8:0:1::1", math.MaxUint64},
+		{"2001:db8::/32", math.MaxUint64},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			r, ok, err := ParseRange(tc.input)
+			if err != nil || !ok {
+				t.Fatalf("expected a range, got %v", err)
+			}
+			if size := r.Size(); size != tc.expected {
+				t.Errorf("expected %d, got %d", tc.expected, size)
+			}
+		})
+	}
+}
+
+func TestWithPorts(t *testing.T) {
+	urls := WithPorts([]string{"https://example.com", "https://example.com:8080", "https://example.org/admin"}, []string{"443", "8443"})
+	expected := []string{
+		"https://example.com:443", "https://example.com:8443",
+		"https://example.com:8080",
+		"https://example.org:443/admin", "https://example.org:8443/admin",
+	}
+	if !slices.Equal(urls, expected) {
+		t.Errorf("expected %v, got %v", expected, urls)
+	}
+}
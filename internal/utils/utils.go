@@ -2,9 +2,15 @@ package utils
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
+	"hash/fnv"
+	"io"
+	"iter"
+	"math/bits"
 	"os"
 	"regexp"
+	"unicode"
 
 	"strings"
 
@@ -33,6 +39,18 @@ func ReadURLsFromFile(urlFile string) ([]string, error) {
 	return urls, nil
 }
 
+// Lines yields the non-empty lines of r, trimmed, until it ends or fails
+func Lines(r io.Reader) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" && !yield(line) {
+				return
+			}
+		}
+	}
+}
+
 func GetHTTPTitleFromBody(body []byte) string {
 	re := regexp.MustCompile(`<title[^>]*>([^<]+)</title>`)
 	matches := re.FindSubmatch(body)
@@ -50,3 +68,60 @@ func ValidateDomain(domain string) error {
 
 	return nil
 }
+
+// Simhash computes a 64-bit locality-sensitive hash of the body, so that
+// similar bodies produce hashes with a small Hamming distance
+func Simhash(body []byte) uint64 {
+	var weights [64]int
+	for _, token := range bytes.FieldsFunc(body, isTokenSeparator) {
+		h := fnv.New64a()
+		h.Write(token)
+		sum := h.Sum64()
+		for i := range 64 {
+			if sum&(1<<uint(i)) != 0 {
+				weights[i]++
+			} else {
+				weights[i]--
+			}
+		}
+	}
+
+	var hash uint64
+	for i, weight := range weights {
+		if weight > 0 {
+			hash |= 1 << uint(i)
+		}
+	}
+	return hash
+}
+
+// HammingDistance returns the number of differing bits between two simhashes
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+func isTokenSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+// IPNetwork returns the network ("ip4" or "ip6") to restrict address lookups to, or "" for both
+// families. The -4, -6 and --dual-stack modes exclude each other.
+func IPNetwork(ipv4, ipv6, dualStack bool) (string, error) {
+	set := 0
+	for _, enabled := range []bool{ipv4, ipv6, dualStack} {
+		if enabled {
+			set++
+		}
+	}
+	if set > 1 {
+		return "", fmt.Errorf("[!] -4, -6 and --dual-stack cannot be combined")
+	}
+
+	switch {
+	case ipv4:
+		return "ip4", nil
+	case ipv6:
+		return "ip6", nil
+	}
+	return "", nil
+}
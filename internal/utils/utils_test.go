@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestSimhash(t *testing.T) {
+	testCases := []struct {
+		name        string
+		a           string
+		b           string
+		maxDistance int
+		minDistance int
+	}{
+		{
+			name:        "Identical bodies",
+			a:           "<html><title>Not Found</title><body>The page /abc was not found</body></html>",
+			b:           "<html><title>Not Found</title><body>The page /abc was not found</body></html>",
+			maxDistance: 0,
+		},
+		{
+			name:        "Bodies differing by a reflected path",
+			a:           "<html><title>Home</title><body>Welcome to our shop. Browse products, offers and news. Path: 1f3a9c</body></html>",
+			b:           "<html><title>Home</title><body>Welcome to our shop. Browse products, offers and news. Path: 77be02</body></html>",
+			maxDistance: 8,
+		},
+		{
+			name:        "Unrelated bodies",
+			a:           "<html><title>Admin login</title><body>Username Password Sign in</body></html>",
+			b:           `{"status":"ok","version":"1.2.3","uptime":12345}`,
+			minDistance: 13,
+			maxDistance: 64,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			distance := HammingDistance(Simhash([]byte(tc.a)), Simhash([]byte(tc.b)))
+			if distance > tc.maxDistance || distance < tc.minDistance {
+				t.Errorf("expected distance in [%d, %d], got %d", tc.minDistance, tc.maxDistance, distance)
+			}
+		})
+	}
+}
@@ -1,12 +1,20 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"time"
 
+	"github.com/GraveSIN/http-probe/internal/diff"
 	"github.com/GraveSIN/http-probe/internal/dnsprobe"
+	"github.com/GraveSIN/http-probe/internal/monitor"
 	"github.com/GraveSIN/http-probe/internal/printer"
 	"github.com/GraveSIN/http-probe/internal/probe"
+	"github.com/GraveSIN/http-probe/internal/serve"
+	"github.com/GraveSIN/http-probe/internal/utils"
+	"github.com/GraveSIN/http-probe/internal/validator"
 	"github.com/spf13/cobra"
 )
 
@@ -24,8 +32,84 @@ func main() {
 	cmd.Flags().BoolP("dns", "", false, "Enable DNS probing instead of HTTP")
 	cmd.Flags().IntP("threads", "t", 10, "Number of concurrent threads")
 	cmd.Flags().StringP("output", "o", "", "Output file path")
-	cmd.Flags().StringP("data", "d", "", "HTTP request body data")
+	cmd.Flags().BoolP("json", "", false, "Write results as JSON lines")
+	cmd.Flags().BoolP("http3", "", false, "Attempt QUIC connections to the HTTP/3 endpoints advertised in Alt-Svc")
+	cmd.Flags().BoolP("tls-scan", "", false, "Enumerate the TLS versions, cipher suites and curves accepted by HTTPS targets")
+	cmd.Flags().BoolP("tls-fingerprint", "", false, "Compute the JARM and JA3S fingerprints of HTTPS targets")
+	cmd.Flags().BoolP("discover", "", false, "Probe the in-scope hostnames found in certificates, CSP, redirects and links")
+	cmd.Flags().IntP("discover-depth", "", 2, "Maximum number of discovery hops from a seed target")
+	cmd.Flags().BoolP("http2", "", false, "Use HTTP/2 when the server supports it (ALPN for HTTPS, h2c upgrade for HTTP)")
+	cmd.Flags().StringP("data", "d", "", "HTTP request body data (@file to read it from a file)")
+	cmd.Flags().StringP("data-binary", "", "", "HTTP request body sent as-is (@file to read it from a file)")
+	cmd.Flags().StringP("content-type", "", "", "Content-Type of the request body (default: detected)")
+	cmd.Flags().StringP("raw-request", "", "", "File containing a raw HTTP request to replay against every target")
+	cmd.Flags().StringArrayP("form", "F", []string{}, "Multipart form field name=value, or name=@file to upload a file")
 	cmd.Flags().IntP("timeout", "T", 10, "Timeout in seconds for each HTTP request or each DNS record's resolution (default: 10)")
+	cmd.Flags().StringSliceP("path", "", []string{}, "Path(s) to probe on every target URL")
+	cmd.Flags().StringP("paths", "", "", "File containing paths to probe on every target URL (one per line)")
+	cmd.Flags().StringP("auth", "", "", "Credentials as basic:user:pass, digest:user:pass or ntlm:DOMAIN\\user:pass")
+	cmd.Flags().StringP("bearer", "", "", "Bearer token sent in the Authorization header")
+	cmd.Flags().StringArrayP("resolve", "", []string{}, "Connect to ip instead of resolving host:port, keeping the hostname for Host and SNI (host:port:ip)")
+	cmd.Flags().StringP("hosts-file", "", "", "Hosts file style overrides (\"ip hostname...\" per line) used before DNS")
+	cmd.Flags().BoolP("ipv4", "4", false, "Only connect to and resolve IPv4 addresses")
+	cmd.Flags().BoolP("ipv6", "6", false, "Only connect to and resolve IPv6 addresses")
+	cmd.Flags().BoolP("dual-stack", "", false, "Probe every target over IPv4 and IPv6 and report its IPv6 readiness")
+	cmd.Flags().StringSliceP("source-ip", "", []string{}, "Local address(es) to send probes and DNS queries from, used in round-robin")
+	cmd.Flags().StringP("interface", "", "", "Network interface whose addresses probes and DNS queries are sent from")
+	cmd.Flags().BoolP("all-ips", "", false, "Probe every address a host resolves to and report the ones that disagree")
+	cmd.Flags().StringP("vhost", "", "", "Wordlist of hostnames to send to every target to discover its virtual hosts")
+	cmd.Flags().StringSliceP("ports", "", []string{}, "Port(s) to probe on every target without an explicit port")
+	cmd.Flags().StringSliceP("exclude", "", []string{}, "IP address(es), range(s) or CIDR prefix(es) to leave out of expanded targets")
+	cmd.Flags().StringP("asn-file", "", "", "Offline ASN to prefix list (\"AS13335 1.1.1.0/24\" per line) to expand AS numbers")
+	cmd.Flags().StringP("scope", "", "", "File of in-scope hosts: domains with * wildcards, /regexes/ and CIDR ranges")
+	cmd.Flags().StringP("out-of-scope", "", "", "File of out-of-scope hosts, never probed even when in scope")
+	cmd.Flags().StringP("auth-file", "", "", "File containing per-host credentials (one \"host scheme:credentials\" per line)")
+	cmd.Flags().BoolP("auth-http-fallback", "", false, "Fall back from HTTPS to plain HTTP for targets with credentials or a client certificate, sending them in cleartext")
+	cmd.Flags().StringP("cert", "", "", "Client certificate file (PEM) for mutual TLS")
+	cmd.Flags().StringP("key", "", "", "Client private key file (PEM) for mutual TLS")
+	cmd.Flags().StringP("cacert", "", "", "CA certificates file (PEM) to verify servers against")
+	cmd.Flags().BoolP("methods-scan", "", false, "Discover allowed HTTP methods with OPTIONS and every common method")
+	cmd.Flags().StringSliceP("extra-methods", "", []string{}, "Custom verb(s) to try in methods scan mode")
+	cmd.Flags().BoolP("cookies", "", false, "Show every cookie with its attributes and issues")
+	cmd.Flags().BoolP("cors", "", false, "Check for CORS misconfigurations with crafted Origin headers")
+	cmd.Flags().BoolP("security-headers", "", false, "Audit security headers (HSTS, CSP, framing, ...) and grade each response")
+	cmd.Flags().BoolP("soft-404", "", false, "Detect soft-404 and wildcard responses by comparing against random paths on each host")
+
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve probes as Prometheus metrics on /probe?target=...&module=...",
+		Run:   runServe,
+	}
+	serveCmd.Flags().StringP("listen", "", ":9115", "Address to listen on")
+	serveCmd.Flags().StringP("config", "", "", "JSON file defining the probe modules (default: http_2xx and dns)")
+	cmd.AddCommand(serveCmd)
+
+	monitorCmd := &cobra.Command{
+		Use:   "monitor",
+		Short: "Re-probe targets on an interval and emit JSON events when they change",
+		Run:   runMonitor,
+	}
+	monitorCmd.Flags().StringSliceP("url", "u", []string{}, "Target URL(s) to monitor")
+	monitorCmd.Flags().StringP("file", "f", "", "File containing URLs (one per line)")
+	monitorCmd.Flags().DurationP("interval", "i", 5*time.Minute, "Time between two probes of the targets")
+	monitorCmd.Flags().IntP("threads", "t", 10, "Number of concurrent threads")
+	monitorCmd.Flags().IntP("timeout", "T", 10, "Timeout in seconds for each HTTP request or each DNS record's resolution")
+	monitorCmd.Flags().StringP("state", "", "", "File keeping the last observations across runs")
+	monitorCmd.Flags().BoolP("once", "", false, "Probe the targets once, compare with the state file and exit")
+	monitorCmd.Flags().StringSliceP("webhook", "", []string{}, "URL(s) to POST every event to as JSON")
+	monitorCmd.Flags().StringP("events-file", "", "", "File to append every event to as JSON lines")
+	monitorCmd.Flags().StringP("exec", "", "", "Shell command to run for every event, with the event as JSON on stdin")
+	cmd.AddCommand(monitorCmd)
+
+	diffCmd := &cobra.Command{
+		Use:   "diff old.jsonl new.jsonl",
+		Short: "Report the new, removed and changed assets between two --json scans",
+		Args:  cobra.ExactArgs(2),
+		Run:   runDiff,
+	}
+	diffCmd.Flags().StringP("format", "", "human", "Output format: human, json or markdown")
+	diffCmd.Flags().StringP("output", "o", "", "Output file path")
+	cmd.AddCommand(diffCmd)
 
 	if err := cmd.Execute(); err != nil {
 		fmt.Println(err)
@@ -49,7 +133,7 @@ func runProbe(cmd *cobra.Command, _ []string) {
 
 		resultsChannel := dnsProber.Start()
 
-		printer.StreamDNSProbeResults(resultsChannel, config.OutputFile)
+		printer.StreamDNSProbeResults(resultsChannel, config.OutputFile, config.JSONOutput)
 
 	case false:
 		// do HTTP probe
@@ -62,7 +146,132 @@ func runProbe(cmd *cobra.Command, _ []string) {
 		prober := probe.NewProber(config)
 		resultsChannel := prober.Start()
 
-		printer.StreamProbeResults(resultsChannel, config.OutputFile)
+		printer.StreamProbeResults(resultsChannel, config.OutputFile, config.JSONOutput, config.ShowCookies)
+	}
+
+}
+
+func runServe(cmd *cobra.Command, _ []string) {
+	listen, _ := cmd.Flags().GetString("listen")
+	configFile, _ := cmd.Flags().GetString("config")
+
+	config, err := serve.LoadConfig(configFile)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("[+] serving probes on %s\n", listen)
+	if err := serve.New(config).ListenAndServe(listen); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func runMonitor(cmd *cobra.Command, _ []string) {
+	urls, _ := cmd.Flags().GetStringSlice("url")
+	urlFile, _ := cmd.Flags().GetString("file")
+	interval, _ := cmd.Flags().GetDuration("interval")
+	threads, _ := cmd.Flags().GetInt("threads")
+	timeout, _ := cmd.Flags().GetInt("timeout")
+	stateFile, _ := cmd.Flags().GetString("state")
+	once, _ := cmd.Flags().GetBool("once")
+	webhooks, _ := cmd.Flags().GetStringSlice("webhook")
+	eventsFile, _ := cmd.Flags().GetString("events-file")
+	command, _ := cmd.Flags().GetString("exec")
+
+	if urlFile != "" {
+		urlsFromFile, err := utils.ReadURLsFromFile(urlFile)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		urls = append(urls, urlsFromFile...)
+	}
+	validURLs, err := validator.ConvertDomainsToURLsAndReturnValidURLs(&urls)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if len(validURLs) == 0 {
+		fmt.Println("[!] at least one URL is required via -u or -f")
+		os.Exit(1)
+	}
+	if interval <= 0 {
+		fmt.Println("[!] --interval must be positive")
+		os.Exit(1)
 	}
 
+	// events always go to stdout, and to every configured sink
+	sinks := []monitor.Sink{monitor.NewWriterSink(os.Stdout)}
+	if eventsFile != "" {
+		sink, err := monitor.NewFileSink(eventsFile)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		sinks = append(sinks, sink)
+	}
+	for _, webhook := range webhooks {
+		sinks = append(sinks, monitor.NewWebhookSink(webhook))
+	}
+	if command != "" {
+		sinks = append(sinks, &monitor.CommandSink{Command: command})
+	}
+
+	m, err := monitor.New(&monitor.Config{
+		URLs:      validURLs,
+		Interval:  interval,
+		Threads:   threads,
+		Timeout:   timeout,
+		StateFile: stateFile,
+		Sinks:     sinks,
+	})
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if once {
+		err = m.Cycle(ctx)
+	} else {
+		err = m.Run(ctx)
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func runDiff(cmd *cobra.Command, args []string) {
+	format, _ := cmd.Flags().GetString("format")
+	outputFile, _ := cmd.Flags().GetString("output")
+
+	oldScan, err := diff.Load(args[0])
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	newScan, err := diff.Load(args[1])
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	output := os.Stdout
+	if outputFile != "" {
+		output, err = os.Create(outputFile)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer output.Close()
+	}
+
+	if err := diff.Compare(oldScan, newScan).Write(output, format); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,267 @@
+// Package dnsprobe looks up the DNS records of domains from Go programs, with the same engine as
+// http-probe --dns.
+//
+//	prober, err := dnsprobe.New(dnsprobe.WithTimeout(2 * time.Second))
+//	if err != nil {
+//		return err
+//	}
+//	result, err := prober.Probe(ctx, "example.com")
+package dnsprobe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"iter"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/GraveSIN/http-probe/internal/dnsprobe"
+	"github.com/GraveSIN/http-probe/internal/source"
+	"github.com/GraveSIN/http-probe/internal/utils"
+)
+
+// Result holds the records of one domain, with the fields written by http-probe --dns --json
+type Result struct {
+	Domain      string   `json:"domain"`
+	TXTRecords  []string `json:"txt_records,omitempty"`
+	NSRecords   []string `json:"ns_records,omitempty"`
+	ARecords    []string `json:"a_records,omitempty"`
+	AAAARecords []string `json:"aaaa_records,omitempty"`
+	MXRecords   []string `json:"mx_records,omitempty"`
+	// DualStack is set with WithDualStack, true when the domain has both A and AAAA records
+	DualStack *bool `json:"dual_stack,omitempty"`
+	// Err is why the domain got no result. It is only set on the results Run streams for the domains
+	// that failed, which have nothing else than Domain set.
+	Err error `json:"-"`
+}
+
+// newResult converts a result of the internal prober
+func newResult(result dnsprobe.DNSProbeResult) Result {
+	return Result{
+		Domain:      result.Domain,
+		TXTRecords:  result.TXTRecords,
+		NSRecords:   result.NSRecords,
+		ARecords:    result.ARecords,
+		AAAARecords: result.AAAARecords,
+		MXRecords:   result.MXRecords,
+		DualStack:   result.DualStack,
+	}
+}
+
+// Source yields the domains of a run
+type Source = iter.Seq[string]
+
+// FromSlice returns a source yielding domains in order
+func FromSlice(domains []string) Source {
+	return slices.Values(domains)
+}
+
+// FromReader returns a source yielding the non-empty lines of r
+func FromReader(r io.Reader) Source {
+	return utils.Lines(r)
+}
+
+// Hooks are called around every lookup. Embed NopHooks to implement only some of them.
+type Hooks interface {
+	// BeforeProbe is called with the domain about to be looked up; returning an error skips it
+	BeforeProbe(ctx context.Context, domain string) error
+	// AfterProbe is called with every result before it is returned
+	AfterProbe(ctx context.Context, result *Result)
+}
+
+// NopHooks implements Hooks without doing anything
+type NopHooks struct{}
+
+func (NopHooks) BeforeProbe(context.Context, string) error { return nil }
+func (NopHooks) AfterProbe(context.Context, *Result)       {}
+
+// settings collect the options before the prober is created
+type settings struct {
+	config    dnsprobe.DNSProbeConfig
+	sourceIPs []string
+	iface     string
+	hooks     []Hooks
+	// the address family options, which exclude each other
+	ipv4, ipv6 bool
+}
+
+// Option configures a Prober
+type Option func(*settings) error
+
+// WithThreads sets how many domains Run looks up at once, 10 by default
+func WithThreads(threads int) Option {
+	return func(s *settings) error {
+		if threads < 1 {
+			return fmt.Errorf("dnsprobe: invalid thread count %d", threads)
+		}
+		s.config.Threads = threads
+		return nil
+	}
+}
+
+// WithTimeout sets the timeout of each record's resolution, rounded up to the second, 10 seconds by default
+func WithTimeout(timeout time.Duration) Option {
+	return func(s *settings) error {
+		if timeout <= 0 {
+			return fmt.Errorf("dnsprobe: invalid timeout %s", timeout)
+		}
+		s.config.Timeout = int(math.Ceil(timeout.Seconds()))
+		return nil
+	}
+}
+
+// WithIPv4Only only looks up A records
+func WithIPv4Only() Option {
+	return func(s *settings) error {
+		s.ipv4 = true
+		return nil
+	}
+}
+
+// WithIPv6Only only looks up AAAA records
+func WithIPv6Only() Option {
+	return func(s *settings) error {
+		s.ipv6 = true
+		return nil
+	}
+}
+
+// WithDualStack reports whether each domain has both A and AAAA records
+func WithDualStack() Option {
+	return func(s *settings) error {
+		s.config.DualStack = true
+		return nil
+	}
+}
+
+// WithSourceIPs sends queries from the given local addresses, in round-robin
+func WithSourceIPs(ips ...string) Option {
+	return func(s *settings) error {
+		s.sourceIPs = append(s.sourceIPs, ips...)
+		return nil
+	}
+}
+
+// WithInterface sends queries from the addresses of a network interface
+func WithInterface(name string) Option {
+	return func(s *settings) error {
+		s.iface = name
+		return nil
+	}
+}
+
+// WithHooks calls hooks around every lookup, in the order they are given
+func WithHooks(hooks ...Hooks) Option {
+	return func(s *settings) error {
+		s.hooks = append(s.hooks, hooks...)
+		return nil
+	}
+}
+
+// Prober looks up the records of domains. It is safe for concurrent use.
+type Prober struct {
+	prober  *dnsprobe.DNSProber
+	threads int
+	hooks   []Hooks
+}
+
+// New creates a prober with the given options
+func New(options ...Option) (*Prober, error) {
+	settings := &settings{config: dnsprobe.DNSProbeConfig{Domains: &[]string{}, Threads: 10, Timeout: 10}}
+	for _, option := range options {
+		if err := option(settings); err != nil {
+			return nil, err
+		}
+	}
+	ipNetwork, err := utils.IPNetwork(settings.ipv4, settings.ipv6, settings.config.DualStack)
+	if err != nil {
+		return nil, fmt.Errorf("dnsprobe: WithIPv4Only, WithIPv6Only and WithDualStack can't be combined")
+	}
+
+	sources, err := source.New(settings.sourceIPs, settings.iface)
+	if err != nil {
+		return nil, err
+	}
+	config := settings.config
+	config.IPNetwork = ipNetwork
+	config.Sources = sources
+
+	return &Prober{prober: dnsprobe.NewDNSProber(&config), threads: config.Threads, hooks: settings.hooks}, nil
+}
+
+// Probe looks up the records of domain. Missing records are left empty rather than reported as errors.
+// When ctx is done before the lookups are, Probe returns ctx.Err() and they finish in the background.
+func (p *Prober) Probe(ctx context.Context, domain string) (Result, error) {
+	if err := utils.ValidateDomain(domain); err != nil {
+		return Result{}, fmt.Errorf("dnsprobe: %w", err)
+	}
+	for _, hooks := range p.hooks {
+		if err := hooks.BeforeProbe(ctx, domain); err != nil {
+			return Result{}, err
+		}
+	}
+
+	done := make(chan dnsprobe.DNSProbeResult, 1)
+	go func() {
+		done <- p.prober.ProbeDomain(domain)
+	}()
+
+	var result Result
+	select {
+	case <-ctx.Done():
+		return Result{}, ctx.Err()
+	case probed := <-done:
+		result = newResult(probed)
+	}
+
+	for _, hooks := range p.hooks {
+		hooks.AfterProbe(ctx, &result)
+	}
+	return result, nil
+}
+
+// Run looks up the domains of source concurrently and streams their results. Domains that fail are
+// streamed with Err set to the error Probe would return. The channel is closed once every domain is looked up or ctx is done.
+func (p *Prober) Run(ctx context.Context, source Source) <-chan Result {
+	domains := make(chan string)
+	results := make(chan Result, p.threads)
+
+	go func() {
+		defer close(domains)
+		for domain := range source {
+			select {
+			case domains <- domain:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var waitGroup sync.WaitGroup
+	for range p.threads {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for domain := range domains {
+				result, err := p.Probe(ctx, domain)
+				if err != nil {
+					result = Result{Domain: domain, Err: err}
+				}
+				select {
+				case results <- result:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		waitGroup.Wait()
+		close(results)
+	}()
+	return results
+}
@@ -0,0 +1,47 @@
+package dnsprobe
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+type skipHooks struct {
+	NopHooks
+}
+
+func (skipHooks) BeforeProbe(context.Context, string) error {
+	return errors.New("skipped by hook")
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(WithIPv6Only(), WithDualStack()); err == nil {
+		t.Error("expected an error combining WithIPv6Only and WithDualStack")
+	}
+	if _, err := New(WithIPv4Only(), WithIPv6Only()); err == nil {
+		t.Error("expected an error combining WithIPv4Only and WithIPv6Only")
+	}
+	if _, err := New(WithThreads(0)); err == nil {
+		t.Error("expected an error for an invalid thread count")
+	}
+
+	prober, err := New(WithHooks(skipHooks{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := prober.Probe(context.Background(), "example.com"); err == nil {
+		t.Error("expected BeforeProbe to skip the domain")
+	}
+	var failed []string
+	for result := range prober.Run(context.Background(), FromSlice([]string{"example.com", "example.org"})) {
+		if result.Err == nil {
+			t.Errorf("expected the skipped domain %s to be streamed with its error", result.Domain)
+		}
+		failed = append(failed, result.Domain)
+	}
+	slices.Sort(failed)
+	if !slices.Equal(failed, []string{"example.com", "example.org"}) {
+		t.Errorf("expected both domains to fail, got %v", failed)
+	}
+}
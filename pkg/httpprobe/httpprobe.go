@@ -0,0 +1,197 @@
+// Package httpprobe probes HTTP targets from Go programs, with the same engine as the http-probe command.
+//
+//	prober, err := httpprobe.New(httpprobe.WithTimeout(5*time.Second), httpprobe.WithSecurityHeaders())
+//	if err != nil {
+//		return err
+//	}
+//	result, err := prober.Probe(ctx, "example.com")
+//
+// Run probes a stream of targets concurrently, with Err set on the results of the ones that failed:
+//
+//	for result := range prober.Run(ctx, httpprobe.FromReader(os.Stdin)) {
+//		if result.Err != nil {
+//			log.Println(result.URL, result.Err)
+//			continue
+//		}
+//		fmt.Println(result.URL, result.StatusLine)
+//	}
+package httpprobe
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"slices"
+	"sync"
+
+	"github.com/GraveSIN/http-probe/internal/probe"
+	"github.com/GraveSIN/http-probe/internal/utils"
+	"github.com/GraveSIN/http-probe/internal/validator"
+)
+
+// ErrNoResponse is returned by Probe when the target didn't answer over HTTPS nor HTTP
+var ErrNoResponse = errors.New("httpprobe: no response")
+
+// Source yields the targets of a run: domains or http(s) URLs
+type Source = iter.Seq[string]
+
+// FromSlice returns a source yielding targets in order
+func FromSlice(targets []string) Source {
+	return slices.Values(targets)
+}
+
+// FromReader returns a source yielding the non-empty lines of r
+func FromReader(r io.Reader) Source {
+	return utils.Lines(r)
+}
+
+// Hooks are called around every probe. Embed NopHooks to implement only some of them.
+type Hooks interface {
+	// BeforeProbe is called with the URL about to be probed; returning an error skips it
+	BeforeProbe(ctx context.Context, url string) error
+	// AfterProbe is called with every result before it is returned
+	AfterProbe(ctx context.Context, result *Result)
+}
+
+// NopHooks implements Hooks without doing anything
+type NopHooks struct{}
+
+func (NopHooks) BeforeProbe(context.Context, string) error { return nil }
+func (NopHooks) AfterProbe(context.Context, *Result)       {}
+
+// Prober probes HTTP targets. It is safe for concurrent use and reuses connections across calls.
+type Prober struct {
+	prober  *probe.Prober
+	threads int
+	hooks   []Hooks
+}
+
+// New creates a prober with the given options
+func New(options ...Option) (*Prober, error) {
+	settings := defaultSettings()
+	for _, option := range options {
+		if err := option(settings); err != nil {
+			return nil, err
+		}
+	}
+
+	config, err := settings.proberConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &Prober{prober: probe.NewProber(config), threads: config.Threads, hooks: settings.hooks}, nil
+}
+
+// Probe probes a single target, a domain or an http(s) URL, trying HTTPS first and falling back to HTTP.
+// Targets that are out of scope are returned with Skipped set. With WithDualStack, the IPv4 result is
+// returned, or the IPv6 one when IPv4 got no response; Run streams both. When ctx is done before the
+// probe is, Probe returns ctx.Err() and the request finishes in the background within the prober's timeout.
+func (p *Prober) Probe(ctx context.Context, target string) (Result, error) {
+	results, err := p.probe(ctx, target)
+	if err != nil {
+		return Result{}, err
+	}
+	return results[0], nil
+}
+
+// probe probes target and returns its results, one per address family with WithDualStack
+func (p *Prober) probe(ctx context.Context, target string) ([]Result, error) {
+	urls, err := validator.ConvertDomainsToURLsAndReturnValidURLs(&[]string{target})
+	if err != nil {
+		return nil, fmt.Errorf("httpprobe: %w", err)
+	}
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("httpprobe: unsupported target %q", target)
+	}
+	url := urls[0]
+
+	for _, hooks := range p.hooks {
+		if err := hooks.BeforeProbe(ctx, url); err != nil {
+			return nil, err
+		}
+	}
+
+	done := make(chan []probe.ProbeResult, 1)
+	go func() {
+		done <- p.prober.Probe(url)
+	}()
+
+	var probed []probe.ProbeResult
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case probed = <-done:
+	}
+
+	var results []Result
+	var failure string
+	for _, result := range probed {
+		if result.StatusLine == "" && result.Skipped == "" {
+			failure = cmp.Or(failure, result.Error)
+			continue
+		}
+		results = append(results, newResult(result))
+	}
+	if len(results) == 0 {
+		if failure != "" {
+			return nil, fmt.Errorf("%w from %s: %s", ErrNoResponse, url, failure)
+		}
+		return nil, fmt.Errorf("%w from %s", ErrNoResponse, url)
+	}
+
+	for i := range results {
+		for _, hooks := range p.hooks {
+			hooks.AfterProbe(ctx, &results[i])
+		}
+	}
+	return results, nil
+}
+
+// Run probes the targets of source concurrently and streams their results. Targets that fail are
+// streamed with Err set to the error Probe would return. The channel is closed once every target
+// is probed or ctx is done.
+func (p *Prober) Run(ctx context.Context, source Source) <-chan Result {
+	targets := make(chan string)
+	results := make(chan Result, p.threads)
+
+	go func() {
+		defer close(targets)
+		for target := range source {
+			select {
+			case targets <- target:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var waitGroup sync.WaitGroup
+	for range p.threads {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for target := range targets {
+				probed, err := p.probe(ctx, target)
+				if err != nil {
+					probed = []Result{{URL: target, Err: err}}
+				}
+				for _, result := range probed {
+					select {
+					case results <- result:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	go func() {
+		waitGroup.Wait()
+		close(results)
+	}()
+	return results
+}
@@ -0,0 +1,169 @@
+package httpprobe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GraveSIN/http-probe/internal/probe"
+	"github.com/GraveSIN/http-probe/internal/secheaders"
+)
+
+// recordingHooks skips the URLs containing skip and records the titles it sees
+type recordingHooks struct {
+	NopHooks
+	skip   string
+	mu     sync.Mutex
+	titles []string
+}
+
+func (h *recordingHooks) BeforeProbe(_ context.Context, url string) error {
+	if h.skip != "" && strings.Contains(url, h.skip) {
+		return errors.New("skipped by hook")
+	}
+	return nil
+}
+
+func (h *recordingHooks) AfterProbe(_ context.Context, result *Result) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.titles = append(h.titles, result.Title)
+}
+
+func newServer(t *testing.T) *httptest.Server {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "<html><title>Page %s</title></html>", r.URL.Path)
+	}))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestProbe(t *testing.T) {
+	server := newServer(t)
+	hooks := &recordingHooks{skip: "/skipped"}
+	prober, err := New(WithTimeout(5*time.Second), WithSecurityHeaders(), WithHooks(hooks))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := prober.Probe(context.Background(), server.URL+"/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.StatusCode != http.StatusOK || result.Title != "Page /a" || result.SecurityHeaders == nil {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if !slices.Equal(hooks.titles, []string{"Page /a"}) {
+		t.Errorf("expected AfterProbe to see the result, got %v", hooks.titles)
+	}
+
+	if _, err := prober.Probe(context.Background(), server.URL+"/skipped"); err == nil {
+		t.Error("expected BeforeProbe to skip the target")
+	}
+	if _, err := prober.Probe(context.Background(), "ftp://example.com"); err == nil {
+		t.Error("expected an error for an unsupported scheme")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := prober.Probe(ctx, server.URL); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	server := newServer(t)
+	prober, err := New(WithThreads(3), WithTimeout(5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// nothing listens on port 1, so the last target fails
+	source := FromReader(strings.NewReader(server.URL + "/1\n\n" + server.URL + "/2\n" + server.URL + "/3\nhttp://127.0.0.1:1\n"))
+	var titles, failed []string
+	for result := range prober.Run(context.Background(), source) {
+		if result.Err != nil {
+			if !errors.Is(result.Err, ErrNoResponse) {
+				t.Errorf("expected ErrNoResponse, got %v", result.Err)
+			}
+			failed = append(failed, result.URL)
+			continue
+		}
+		titles = append(titles, result.Title)
+	}
+	slices.Sort(titles)
+	if !slices.Equal(titles, []string{"Page /1", "Page /2", "Page /3"}) {
+		t.Errorf("unexpected titles %v", titles)
+	}
+	if !slices.Equal(failed, []string{"http://127.0.0.1:1"}) {
+		t.Errorf("expected the unreachable target to be streamed with its error, got %v", failed)
+	}
+}
+
+func TestNewResult(t *testing.T) {
+	expires := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+	internal := probe.ProbeResult{
+		URL:             "https://example.com",
+		StatusCode:      200,
+		StatusLine:      "200 OK",
+		Title:           "Example",
+		TimeTaken:       time.Second,
+		Methods:         &probe.MethodScanResult{Allow: "GET", Statuses: map[string]int{"GET": 200}, Accepted: []string{"GET"}},
+		SecurityHeaders: &secheaders.Report{Grade: "B", Score: 80, Findings: []secheaders.Finding{{Header: "X-Frame-Options", Severity: "medium", Message: "missing"}}},
+		CORS:            []probe.CORSFinding{{Test: "reflected", Origin: "https://evil.example", AllowOrigin: "https://evil.example", Severity: "high"}},
+		Cookies:         []probe.Cookie{{Name: "session", Secure: true, Expires: &expires, Size: 20, Issues: []string{"no SameSite"}}},
+		Technologies:    []string{"PHP"},
+		ALPN:            []string{"h2"},
+		AltSvc:          []probe.AltService{{Protocol: "h3", Port: "443", MaxAge: 86400}},
+		HTTP3:           []probe.HTTP3Result{{Endpoint: "example.com:443", Success: true, HandshakeTime: time.Millisecond, ALPN: "h3"}},
+		TLSScan:         &probe.TLSScanResult{Versions: []string{"TLS 1.3"}, CipherSuites: map[string][]string{"TLS 1.3": {"TLS_AES_128_GCM_SHA256"}}, Grade: "A"},
+		TLSFingerprint:  &probe.TLSFingerprint{JARM: "jarm", JA3S: "ja3s"},
+		IPv6Readiness:   "ready",
+	}
+
+	// the public result is written like http-probe --json writes the internal one
+	expected, err := json.Marshal(internal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.Marshal(newResult(internal))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(expected) {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	for _, option := range []Option{WithThreads(0), WithTimeout(0), WithAuth("nope"), WithSourceIPs("not-an-ip")} {
+		if _, err := New(option); err == nil {
+			t.Error("expected an error for an invalid option")
+		}
+	}
+
+	conflicts := [][]Option{
+		{WithIPv4Only(), WithIPv6Only()},
+		{WithIPv4Only(), WithDualStack()},
+		{WithIPv6Only(), WithDualStack()},
+	}
+	for _, options := range conflicts {
+		if _, err := New(options...); err == nil {
+			t.Error("expected an error for conflicting address families")
+		}
+	}
+	if _, err := New(WithDualStack(), WithAuthHTTPFallback()); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,265 @@
+package httpprobe
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/GraveSIN/http-probe/internal/probe"
+	"github.com/GraveSIN/http-probe/internal/scope"
+	"github.com/GraveSIN/http-probe/internal/source"
+	"github.com/GraveSIN/http-probe/internal/utils"
+)
+
+// settings collect the options before the prober is created
+type settings struct {
+	config    probe.ProberConfig
+	sourceIPs []string
+	iface     string
+	hooks     []Hooks
+	// the address family options, which exclude each other
+	ipv4, ipv6, dualStack bool
+}
+
+func defaultSettings() *settings {
+	return &settings{config: probe.ProberConfig{
+		URLs:    &[]string{},
+		Threads: 10,
+		Timeout: 10,
+		Method:  "GET",
+	}}
+}
+
+func (s *settings) proberConfig() (*probe.ProberConfig, error) {
+	ipNetwork, err := utils.IPNetwork(s.ipv4, s.ipv6, s.dualStack)
+	if err != nil {
+		return nil, fmt.Errorf("httpprobe: WithIPv4Only, WithIPv6Only and WithDualStack can't be combined")
+	}
+	sources, err := source.New(s.sourceIPs, s.iface)
+	if err != nil {
+		return nil, err
+	}
+	config := s.config
+	config.IPNetwork = ipNetwork
+	config.DualStack = s.dualStack
+	config.Sources = sources
+	return &config, nil
+}
+
+// Option configures a Prober
+type Option func(*settings) error
+
+// WithThreads sets how many targets Run probes at once, 10 by default
+func WithThreads(threads int) Option {
+	return func(s *settings) error {
+		if threads < 1 {
+			return fmt.Errorf("httpprobe: invalid thread count %d", threads)
+		}
+		s.config.Threads = threads
+		return nil
+	}
+}
+
+// WithTimeout sets the timeout of each request, rounded up to the second, 10 seconds by default
+func WithTimeout(timeout time.Duration) Option {
+	return func(s *settings) error {
+		if timeout <= 0 {
+			return fmt.Errorf("httpprobe: invalid timeout %s", timeout)
+		}
+		s.config.Timeout = int(math.Ceil(timeout.Seconds()))
+		return nil
+	}
+}
+
+// WithMethod sets the HTTP method, GET by default
+func WithMethod(method string) Option {
+	return func(s *settings) error {
+		s.config.Method = method
+		return nil
+	}
+}
+
+// WithBody sends body with every request, with {{url}}, {{host}} and {{random}} substituted for each
+// target. The content type is detected when empty.
+func WithBody(body, contentType string) Option {
+	return func(s *settings) error {
+		s.config.Body = body
+		s.config.ContentType = contentType
+		return nil
+	}
+}
+
+// WithAuth authenticates with a value in the --auth syntax: basic:user:pass, digest:user:pass,
+// ntlm:DOMAIN\user:pass or bearer:token
+func WithAuth(value string) Option {
+	return func(s *settings) error {
+		credentials, err := probe.ParseCredentials(value)
+		if err != nil {
+			return fmt.Errorf("httpprobe: %w", err)
+		}
+		s.config.Credentials = credentials
+		return nil
+	}
+}
+
+// WithAuthHTTPFallback lets requests with credentials fall back from HTTPS to HTTP, sending the
+// credentials in cleartext. Without it, targets probed WithAuth or WithClientCertificate
+// are only tried over HTTPS.
+func WithAuthHTTPFallback() Option {
+	return func(s *settings) error {
+		s.config.AuthHTTPFallback = true
+		return nil
+	}
+}
+
+// WithClientCertificate presents certificate to servers asking for mutual TLS
+func WithClientCertificate(certificate tls.Certificate) Option {
+	return func(s *settings) error {
+		s.config.ClientCertificate = &certificate
+		return nil
+	}
+}
+
+// WithRootCAs verifies servers against pool instead of accepting any certificate
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(s *settings) error {
+		s.config.RootCAs = pool
+		return nil
+	}
+}
+
+// WithScope only probes the hosts matching the allow rules, and never the ones matching the deny rules.
+// Rules use the syntax of --scope files: domains with * wildcards, /regexes/, IP addresses and CIDR ranges.
+func WithScope(allow, deny []string) Option {
+	return func(s *settings) error {
+		targetScope, err := scope.New(allow, deny)
+		if err != nil {
+			return fmt.Errorf("httpprobe: %w", err)
+		}
+		s.config.Scope = targetScope
+		return nil
+	}
+}
+
+// WithIPv4Only only connects to IPv4 addresses
+func WithIPv4Only() Option {
+	return func(s *settings) error {
+		s.ipv4 = true
+		return nil
+	}
+}
+
+// WithIPv6Only only connects to IPv6 addresses
+func WithIPv6Only() Option {
+	return func(s *settings) error {
+		s.ipv6 = true
+		return nil
+	}
+}
+
+// WithDualStack probes every target over IPv4 and over IPv6 and reports its IPv6 readiness
+func WithDualStack() Option {
+	return func(s *settings) error {
+		s.dualStack = true
+		return nil
+	}
+}
+
+// WithSourceIPs sends requests from the given local addresses, in round-robin
+func WithSourceIPs(ips ...string) Option {
+	return func(s *settings) error {
+		s.sourceIPs = append(s.sourceIPs, ips...)
+		return nil
+	}
+}
+
+// WithInterface sends requests from the addresses of a network interface
+func WithInterface(name string) Option {
+	return func(s *settings) error {
+		s.iface = name
+		return nil
+	}
+}
+
+// WithHTTP2 uses HTTP/2 when the server supports it
+func WithHTTP2() Option {
+	return func(s *settings) error {
+		s.config.HTTP2 = true
+		return nil
+	}
+}
+
+// WithHTTP3 attempts QUIC connections to the HTTP/3 endpoints advertised in Alt-Svc
+func WithHTTP3() Option {
+	return func(s *settings) error {
+		s.config.HTTP3 = true
+		return nil
+	}
+}
+
+// WithSecurityHeaders audits and grades the security headers of every response
+func WithSecurityHeaders() Option {
+	return func(s *settings) error {
+		s.config.SecurityHeaders = true
+		return nil
+	}
+}
+
+// WithCORS checks for CORS misconfigurations with crafted Origin headers
+func WithCORS() Option {
+	return func(s *settings) error {
+		s.config.CORS = true
+		return nil
+	}
+}
+
+// WithSoft404 flags responses that look like the ones served for random paths
+func WithSoft404() Option {
+	return func(s *settings) error {
+		s.config.Soft404 = true
+		return nil
+	}
+}
+
+// WithTLSScan enumerates the TLS versions, cipher suites and curves of HTTPS targets
+func WithTLSScan() Option {
+	return func(s *settings) error {
+		s.config.TLSScan = true
+		return nil
+	}
+}
+
+// WithTLSFingerprint computes the JARM and JA3S fingerprints of HTTPS targets
+func WithTLSFingerprint() Option {
+	return func(s *settings) error {
+		s.config.TLSFingerprint = true
+		return nil
+	}
+}
+
+// WithMethodsScan discovers the allowed HTTP methods, trying extraMethods too
+func WithMethodsScan(extraMethods ...string) Option {
+	return func(s *settings) error {
+		s.config.MethodsScan = true
+		s.config.ExtraMethods = extraMethods
+		return nil
+	}
+}
+
+// WithHashes records the SHA-256 of every body and certificate
+func WithHashes() Option {
+	return func(s *settings) error {
+		s.config.Hashes = true
+		return nil
+	}
+}
+
+// WithHooks calls hooks around every probe, in the order they are given
+func WithHooks(hooks ...Hooks) Option {
+	return func(s *settings) error {
+		s.hooks = append(s.hooks, hooks...)
+		return nil
+	}
+}
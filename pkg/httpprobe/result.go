@@ -0,0 +1,208 @@
+package httpprobe
+
+import (
+	"time"
+
+	"github.com/GraveSIN/http-probe/internal/probe"
+	"github.com/GraveSIN/http-probe/internal/secheaders"
+)
+
+// Result is the result of probing one URL, with the fields written by http-probe --json
+type Result struct {
+	URL                   string                 `json:"url"`
+	BaseURL               string                 `json:"base_url,omitempty"`
+	Path                  string                 `json:"path,omitempty"`
+	StatusCode            int                    `json:"status_code"`
+	StatusLine            string                 `json:"status_line"`
+	ServerHeader          string                 `json:"server_header,omitempty"`
+	RedirectLocation      string                 `json:"redirect_location,omitempty"`
+	Title                 string                 `json:"title,omitempty"`
+	ContentType           string                 `json:"content_type,omitempty"`
+	ContentLength         int                    `json:"content_length"`
+	PoweredByHeader       string                 `json:"powered_by_header,omitempty"`
+	ContentSecurityPolicy string                 `json:"content_security_policy,omitempty"`
+	TimeTaken             time.Duration          `json:"time_taken"`
+	LikelySoft404         bool                   `json:"likely_soft_404,omitempty"`
+	Methods               *MethodScanResult      `json:"methods,omitempty"`
+	SecurityHeaders       *SecurityHeadersReport `json:"security_headers,omitempty"`
+	CORS                  []CORSFinding          `json:"cors,omitempty"`
+	Cookies               []Cookie               `json:"cookies,omitempty"`
+	Technologies          []string               `json:"technologies,omitempty"`
+	Protocol              string                 `json:"protocol,omitempty"`
+	ALPN                  []string               `json:"alpn,omitempty"`
+	AltSvc                []AltService           `json:"alt_svc,omitempty"`
+	HTTP3                 []HTTP3Result          `json:"http3,omitempty"`
+	TLSScan               *TLSScanResult         `json:"tls_scan,omitempty"`
+	TLSFingerprint        *TLSFingerprint        `json:"tls_fingerprint,omitempty"`
+	DiscoveredFrom        string                 `json:"discovered_from,omitempty"`
+	// Skipped is the reason a target was not probed, when it is out of scope
+	Skipped string `json:"skipped,omitempty"`
+	// DialAddress is the address the request was sent to when it differs from the URL's host
+	DialAddress string `json:"dial_address,omitempty"`
+	// CertificateSHA256 and ContentSHA256 are set with WithHashes
+	CertificateSHA256 string `json:"certificate_sha256,omitempty"`
+	ContentSHA256     string `json:"content_sha256,omitempty"`
+	// IPv6Readiness is set with WithDualStack: ready, mismatch, unreachable or no-aaaa
+	IPv6Readiness string `json:"ipv6_readiness,omitempty"`
+	// Err is why the target got no result. It is only set on the results Run streams for the targets
+	// that failed, which have nothing else than URL set: the target as given to Run.
+	Err error `json:"-"`
+}
+
+// MethodScanResult lists the HTTP methods a URL allows, set with WithMethodsScan
+type MethodScanResult struct {
+	// Allow is the Allow header returned for OPTIONS
+	Allow string `json:"allow,omitempty"`
+	// Statuses maps each tried method to its response status code
+	Statuses map[string]int `json:"statuses"`
+	// Accepted lists methods that did not answer with 405 or 501
+	Accepted []string `json:"accepted,omitempty"`
+	// Dangerous describes risky behaviour such as TRACE echo or unauthenticated PUT
+	Dangerous []string `json:"dangerous,omitempty"`
+}
+
+// SecurityHeadersReport grades the security headers of a response, set with WithSecurityHeaders
+type SecurityHeadersReport struct {
+	Grade    string                  `json:"grade"`
+	Score    int                     `json:"score"`
+	Findings []SecurityHeaderFinding `json:"findings,omitempty"`
+}
+
+// SecurityHeaderFinding is a single issue found in a response's security headers
+type SecurityHeaderFinding struct {
+	Header   string `json:"header"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// CORSFinding is a CORS misconfiguration found with a crafted Origin header, set with WithCORS
+type CORSFinding struct {
+	Test             string `json:"test"`
+	Origin           string `json:"origin"`
+	AllowOrigin      string `json:"allow_origin"`
+	AllowCredentials bool   `json:"allow_credentials"`
+	Severity         string `json:"severity"`
+}
+
+// Cookie is a cookie set by a response, with the issues of its attributes
+type Cookie struct {
+	Name     string     `json:"name"`
+	Domain   string     `json:"domain,omitempty"`
+	Path     string     `json:"path,omitempty"`
+	Secure   bool       `json:"secure"`
+	HTTPOnly bool       `json:"http_only"`
+	SameSite string     `json:"same_site,omitempty"`
+	Expires  *time.Time `json:"expires,omitempty"`
+	MaxAge   int        `json:"max_age,omitempty"`
+	Size     int        `json:"size"`
+	Issues   []string   `json:"issues,omitempty"`
+}
+
+// AltService is an alternative service advertised in an Alt-Svc header
+type AltService struct {
+	Protocol string `json:"protocol"`
+	// Host is empty when the alternative is on the same host
+	Host   string `json:"host,omitempty"`
+	Port   string `json:"port"`
+	MaxAge int    `json:"max_age,omitempty"`
+}
+
+// HTTP3Result is the outcome of a QUIC connection attempt to an advertised HTTP/3 endpoint, set with WithHTTP3
+type HTTP3Result struct {
+	Endpoint      string        `json:"endpoint"`
+	Success       bool          `json:"success"`
+	HandshakeTime time.Duration `json:"handshake_time,omitempty"`
+	QUICVersion   string        `json:"quic_version,omitempty"`
+	ALPN          string        `json:"alpn,omitempty"`
+	Error         string        `json:"error,omitempty"`
+}
+
+// TLSScanResult lists the TLS versions, cipher suites and curves a server accepts, set with WithTLSScan
+type TLSScanResult struct {
+	Versions []string `json:"versions"`
+	// CipherSuites lists the accepted suites per protocol version. TLS 1.3 suites can't be
+	// restricted by the client, so only the one the server picked is reported for it.
+	CipherSuites           map[string][]string `json:"cipher_suites"`
+	ServerCipherPreference bool                `json:"server_cipher_preference"`
+	Curves                 []string            `json:"curves,omitempty"`
+	OCSPStapling           bool                `json:"ocsp_stapling"`
+	SessionResumption      bool                `json:"session_resumption"`
+	Grade                  string              `json:"grade"`
+	Issues                 []string            `json:"issues,omitempty"`
+}
+
+// TLSFingerprint holds the JARM and JA3S fingerprints of a server, set with WithTLSFingerprint
+type TLSFingerprint struct {
+	JARM string `json:"jarm"`
+	// JA3S is computed from the ServerHello answering the TLS 1.3 JARM probe (or the TLS 1.2 one)
+	JA3S    string `json:"ja3s,omitempty"`
+	JA3SRaw string `json:"ja3s_raw,omitempty"`
+}
+
+// newResult converts a result of the internal prober. The nested types mirror the internal ones
+// field for field, so they convert directly and the build breaks when the two drift apart.
+func newResult(result probe.ProbeResult) Result {
+	converted := Result{
+		URL:                   result.URL,
+		BaseURL:               result.BaseURL,
+		Path:                  result.Path,
+		StatusCode:            result.StatusCode,
+		StatusLine:            result.StatusLine,
+		ServerHeader:          result.ServerHeader,
+		RedirectLocation:      result.RedirectLocation,
+		Title:                 result.Title,
+		ContentType:           result.ContentType,
+		ContentLength:         result.ContentLength,
+		PoweredByHeader:       result.PoweredByHeader,
+		ContentSecurityPolicy: result.ContentSecurityPolicy,
+		TimeTaken:             result.TimeTaken,
+		LikelySoft404:         result.LikelySoft404,
+		CORS:                  convertAll(result.CORS, func(finding probe.CORSFinding) CORSFinding { return CORSFinding(finding) }),
+		Cookies:               convertAll(result.Cookies, func(cookie probe.Cookie) Cookie { return Cookie(cookie) }),
+		Technologies:          result.Technologies,
+		Protocol:              result.Protocol,
+		ALPN:                  result.ALPN,
+		AltSvc:                convertAll(result.AltSvc, func(service probe.AltService) AltService { return AltService(service) }),
+		HTTP3:                 convertAll(result.HTTP3, func(http3 probe.HTTP3Result) HTTP3Result { return HTTP3Result(http3) }),
+		DiscoveredFrom:        result.DiscoveredFrom,
+		Skipped:               result.Skipped,
+		DialAddress:           result.DialAddress,
+		CertificateSHA256:     result.CertificateSHA256,
+		ContentSHA256:         result.ContentSHA256,
+		IPv6Readiness:         result.IPv6Readiness,
+	}
+	if result.Methods != nil {
+		methods := MethodScanResult(*result.Methods)
+		converted.Methods = &methods
+	}
+	if result.SecurityHeaders != nil {
+		converted.SecurityHeaders = &SecurityHeadersReport{
+			Grade: result.SecurityHeaders.Grade,
+			Score: result.SecurityHeaders.Score,
+			Findings: convertAll(result.SecurityHeaders.Findings, func(finding secheaders.Finding) SecurityHeaderFinding {
+				return SecurityHeaderFinding(finding)
+			}),
+		}
+	}
+	if result.TLSScan != nil {
+		scan := TLSScanResult(*result.TLSScan)
+		converted.TLSScan = &scan
+	}
+	if result.TLSFingerprint != nil {
+		fingerprint := TLSFingerprint(*result.TLSFingerprint)
+		converted.TLSFingerprint = &fingerprint
+	}
+	return converted
+}
+
+// convertAll converts every item, keeping nil slices nil so omitempty fields stay omitted
+func convertAll[T, U any](items []T, convert func(T) U) []U {
+	if items == nil {
+		return nil
+	}
+	converted := make([]U, len(items))
+	for i, item := range items {
+		converted[i] = convert(item)
+	}
+	return converted
+}